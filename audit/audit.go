@@ -0,0 +1,264 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"template/config"
+	"template/logging"
+	"template/util"
+	"template/util/timeutil"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// DefaultFile is where the audit log goes when the config doesnt say
+const DefaultFile = "./data/audit.jsonl"
+
+// Entry is a single command invocation in the audit log
+type Entry struct {
+	ID        string    `json:"id,omitempty"` // invocation ID (the message or interaction ID)
+	Time      time.Time `json:"time"`
+	Command   string    `json:"command"`
+	Type      string    `json:"type"` // prefix or slash
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	GuildID   string    `json:"guild_id"`
+	ChannelID string    `json:"channel_id"`
+	Args      []string  `json:"args,omitempty"`
+	Admin     bool      `json:"admin"`
+	Result    string    `json:"result"`
+}
+
+// Filter narrows down a query, empty fields match everything
+type Filter struct {
+	UserID  string
+	Command string
+	Since   time.Time
+	Until   time.Time
+	Limit   int // only the newest Limit entries are kept, 0 means no limit
+}
+
+// syncInterval is how often written entries are flushed to disk
+const syncInterval = time.Second
+
+var (
+	lock  sync.Mutex
+	file  *os.File
+	dirty bool          // entries were written since the last sync
+	stop  chan struct{} // closed by Close to stop the syncer
+)
+
+// path returns the audit log file from the config
+func path() string {
+	if config.Config.Audit.File != "" {
+		return config.Config.Audit.File
+	}
+	return DefaultFile
+}
+
+// Open opens the audit log for appending, does nothing when auditing is disabled
+func Open() error {
+	if !config.Config.Audit.Enabled {
+		return nil
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path()), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	file = f
+	stop = make(chan struct{})
+	go syncer(stop)
+	return nil
+}
+
+// syncer flushes the audit log to disk every syncInterval while there is something new
+// syncing on every write made every command wait for the disk, entries still reach the OS straight away
+// so a crash of the bot loses nothing, only a power loss can take the last second with it
+func syncer(stop chan struct{}) {
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			lock.Lock()
+			if file != nil && dirty {
+				if err := file.Sync(); err != nil {
+					logging.Warn("Failed to sync the audit log: %v", err)
+				}
+				dirty = false
+			}
+			lock.Unlock()
+		}
+	}
+}
+
+// Close flushes and closes the audit log
+func Close() error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if file == nil {
+		return nil
+	}
+	close(stop)
+	file.Sync()
+	err := file.Close()
+	file = nil
+	return err
+}
+
+// Record writes an entry to the audit log and mirrors it to the log channel if one is set
+func Record(s *discordgo.Session, e Entry) {
+	if !config.Config.Audit.Enabled {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	if err := write(e); err != nil {
+		logging.Error("Failed to write audit entry for %s: %v", e.Command, err)
+	}
+
+	// by default only admin commands get mirrored so the log channel doesnt drown in .help spam
+	if config.Config.Audit.ChannelID != "" && s != nil && (e.Admin || config.Config.Audit.MirrorAll) {
+		go mirror(s, e)
+	}
+}
+
+// write appends an entry to the file, syncer takes care of getting it onto the disk
+func write(e Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if file == nil {
+		return fmt.Errorf("audit log is not open")
+	}
+	if _, err = file.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	dirty = true
+	return nil
+}
+
+// mirror posts an entry to the audit log channel
+func mirror(s *discordgo.Session, e Entry) {
+	kind := util.ThemeInfo
+	if e.Result != "success" {
+		kind = util.ThemeWarning
+	}
+
+	embed := util.NewThemedEmbed(kind).
+		SetTitle("Command Audit").
+		SetDescription(Format(e)).
+		Truncate()
+
+	if _, err := s.ChannelMessageSendEmbed(config.Config.Audit.ChannelID, embed.MessageEmbed); err != nil {
+		logging.Warn("Failed to mirror audit entry to channel %s: %v", config.Config.Audit.ChannelID, err)
+	}
+}
+
+// Format turns an entry into a line of markdown for embeds
+func Format(e Entry) string {
+	args := ""
+	if len(e.Args) > 0 {
+		args = " `" + strings.Join(e.Args, " ") + "`"
+	}
+	guild := "DM"
+	if e.GuildID != "" {
+		guild = e.GuildID
+	}
+	return fmt.Sprintf("%s <@%s> ran **%s** (%s)%s in <#%s> (guild %s) → `%s`",
+		timeutil.Timestamp(e.Time, timeutil.ShortDateTime), e.UserID, e.Command, e.Type, args, e.ChannelID, guild, e.Result)
+}
+
+// Query reads the audit log and returns the entries matching the filter, newest first
+// big logs take a while to scan so it stops early when the context is canceled
+// it reads through its own handle without the writer lock so commands dont wait on a search, a line that is
+// still being written when we reach the end just fails to parse and gets skipped
+func Query(ctx context.Context, f Filter) ([]Entry, error) {
+	fh, err := os.Open(path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer fh.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(fh)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// a half written line (power loss etc) shouldnt hide the rest of the log
+			continue
+		}
+		if !f.matches(e) {
+			continue
+		}
+		entries = append(entries, e)
+		// the newest entries are at the end so with a limit we only ever need the last Limit of them,
+		// dropping the old half once we have twice that keeps memory flat without copying on every line
+		if f.Limit > 0 && len(entries) >= 2*f.Limit {
+			entries = append(entries[:0], entries[len(entries)-f.Limit:]...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if f.Limit > 0 && len(entries) > f.Limit {
+		entries = entries[len(entries)-f.Limit:]
+	}
+	// the file is oldest first so we flip it around
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// matches reports whether an entry passes the filter
+func (f Filter) matches(e Entry) bool {
+	if f.UserID != "" && e.UserID != f.UserID {
+		return false
+	}
+	if f.Command != "" && !strings.EqualFold(e.Command, f.Command) {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+	return true
+}
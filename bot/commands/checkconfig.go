@@ -1,15 +1,16 @@
 package commands
 
 import (
-	"fmt"
+	"context"
 	"template/config"
 	"template/util"
+	"template/util/templates"
 
 	"github.com/bwmarrin/discordgo"
 )
 
 // CheckConfig shows the current bot configuration in an embed
-func CheckConfig(s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+func CheckConfig(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
 	authenticatedIDs := config.Config.AuthenticatedIds
 	Admins := ""
 	for i, userID := range authenticatedIDs {
@@ -35,16 +36,13 @@ func CheckConfig(s *discordgo.Session, m *discordgo.MessageCreate, args []string
 		deregisterStatus = "✅"
 	}
 
-	embed := util.NewEmbed().
-		SetTitle(fmt.Sprintf("⚙️ %s Configuration", config.Config.Brand.Name)).
-		SetDescription("Bot Configuration and setand tings").
-		AddField("Basic Settings", fmt.Sprintf("**Command Prefix:** `%s`\n**Brand Name:** `%s`", config.Config.Prefix, config.Config.Brand.Name)).
-		AddField("Authenticated Users", Admins).
-		AddField("Command Systems", fmt.Sprintf("**Prefix Commands:** %s\n**Slash Commands:** %s", prefixStatus, slashStatus)).
-		AddField("Advanced Settings", fmt.Sprintf("**Auto-Deregister:** %s", deregisterStatus)).
-		SetColor(255, 255, 255).
-		SetFooter(fmt.Sprintf("%s • Configuration checked by %s", config.Config.Brand.Name, m.Author.Username), config.Config.Brand.Icon).
-		SetThumbnail(config.Config.Brand.Icon).
-		Truncate()
-	s.ChannelMessageSendEmbed(m.ChannelID, embed.MessageEmbed)
+	// the layout lives in config/templates/config.json so it can be restyled without recompiling
+	data := templates.NewData(s, m.GuildID, m.Author).
+		With("admins", Admins).
+		With("prefix_status", prefixStatus).
+		With("slash_status", slashStatus).
+		With("deregister_status", deregisterStatus)
+
+	embed := templates.Render("config", data)
+	util.NewMessageResponder(s, m.Message).Embed(embed.MessageEmbed, false)
 }
@@ -0,0 +1,385 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"template/bot/slashcommands"
+	"template/config"
+	"template/settings"
+	"template/util"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// protectedCommands cant be disabled, turning off the command that turns things back on would lock everyone out
+var protectedCommands = map[string]bool{"command": true}
+
+/*
+Parameters:
+  - ctx (context.Context): the command context (deadline, shutdown cancellation, logger and guild settings)
+  - s (*discordgo.Session): the active Discord session instance
+  - m (*discordgo.MessageCreate): the message that triggered the command
+  - args ([]string): the command and its arguments
+
+Usage:
+  - .command                                          lists the commands that are disabled somewhere
+  - .command disable <name> [global|server|#channel]  turns a command off (the server by default, global in DMs)
+  - .command enable <name> [global|server|#channel]   turns it back on at that level
+  - .command allow <name> <#channel|#category|@role>  only lets the command be used there / by them
+  - .command deny <name> <#channel|#category|@role>   stops the command being used there / by them
+  - .command remove <name> <#channel|#category|@role> takes a channel, category or role off both lists
+  - .command rules <name>                             shows the allow/deny lists from the config and this server
+  - .command clear <name>                             removes every rule this server added with this command
+
+Names work for prefix commands (and their aliases) and slash commands, a name both have is changed for both
+*/
+func ManageCommands(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	r := util.NewMessageResponder(s, m.Message)
+	prefix := util.GuildSettings(ctx).Prefix
+
+	if len(args) < 2 || strings.EqualFold(args[1], "list") {
+		r.Embed(disabledListEmbed(s, m.GuildID), false)
+		return
+	}
+
+	action := strings.ToLower(args[1])
+	usage, known := map[string]string{
+		"enable":  "[global|server|#channel]",
+		"disable": "[global|server|#channel]",
+		"allow":   "<#channel|#category|@role>",
+		"deny":    "<#channel|#category|@role>",
+		"remove":  "<#channel|#category|@role>",
+		"rules":   "",
+		"clear":   "",
+	}[action]
+	if !known {
+		r.Error("Commands", "Unknown option `%s`, use `list`, `enable`, `disable`, `allow`, `deny`, `remove`, `rules` or `clear`", args[1])
+		return
+	}
+	if len(args) < 3 {
+		r.Error("Commands", "Usage: `%scommand %s <name> %s`", prefix, action, usage)
+		return
+	}
+
+	name, ok := commandName(args[2])
+	if !ok {
+		r.Error("Commands", "There is no command called `%s`", args[2])
+		return
+	}
+	if protectedCommands[name] && action != "rules" {
+		r.Error("Commands", "`%s` cant be restricted", name)
+		return
+	}
+
+	switch action {
+	case "allow", "deny", "remove":
+		if len(args) < 4 {
+			r.Error("Commands", "Usage: `%scommand %s <name> %s`", prefix, action, usage)
+			return
+		}
+		changeRule(ctx, r, s, m, action, name, args[3])
+		return
+	case "rules":
+		r.Embed(rulesEmbed(m.GuildID, name), false)
+		return
+	case "clear":
+		changed, err := settings.ClearRules(m.GuildID, name)
+		if err != nil {
+			util.Logger(ctx).Error("Failed to save settings: %v", err)
+			r.Error("Commands", "Something went wrong while saving that, check the logs")
+			return
+		}
+		if !changed {
+			r.Embed(util.NewInfoEmbed("Commands", "`%s` has no rules added with this command (rules in the config stay until the config changes)", name), false)
+			return
+		}
+		r.Embed(util.NewSuccessEmbed("Commands", "Removed every rule added for `%s`", name), false)
+		return
+	}
+
+	where := ""
+	if len(args) > 3 {
+		where = args[3]
+	}
+	level, id, err := disableLevel(s, m, where)
+	if err != nil {
+		r.Error("Commands", "%v", err)
+		return
+	}
+
+	disabled := action == "disable"
+	changed, err := settings.SetDisabled(name, level, id, disabled)
+	if err != nil {
+		util.Logger(ctx).Error("Failed to save settings: %v", err)
+		r.Error("Commands", "Something went wrong while saving that, check the logs")
+		return
+	}
+
+	place := levelName(level, id)
+	if !changed {
+		state := "enabled"
+		if disabled {
+			state = "disabled"
+		}
+		r.Embed(util.NewInfoEmbed("Commands", "`%s` was already %s %s", name, state, place), false)
+		return
+	}
+
+	msg := fmt.Sprintf("`%s` is now disabled %s", name, place)
+	if !disabled {
+		msg = fmt.Sprintf("`%s` is now enabled %s", name, place)
+		// enabling only undoes one level so we say when something wider still keeps it off here
+		channelIDs := util.ChannelIDs(s, m.ChannelID)
+		if still, stillLevel := settings.DisabledAt(name, m.GuildID, channelIDs); still {
+			msg += fmt.Sprintf("\nIt is still disabled %s though", levelName(stillLevel, stillID(name, stillLevel, m, channelIDs)))
+		}
+	}
+	r.Embed(util.NewSuccessEmbed("Commands", "%s", msg), false)
+}
+
+// changeRule adds a channel, category or role to an allow or deny list, or with remove takes it off both
+func changeRule(ctx context.Context, r *util.Responder, s *discordgo.Session, m *discordgo.MessageCreate, action, name, target string) {
+	if m.GuildID == "" {
+		r.Error("Commands", "Rules can only be changed from a server")
+		return
+	}
+	kind, id, err := ruleTarget(s, m.GuildID, target)
+	if err != nil {
+		r.Error("Commands", "%v", err)
+		return
+	}
+
+	var changed bool
+	if action == "remove" {
+		for _, mode := range []string{settings.RuleAllow, settings.RuleDeny} {
+			removed, err := settings.SetRule(m.GuildID, name, mode, kind, id, false)
+			if err != nil {
+				util.Logger(ctx).Error("Failed to save settings: %v", err)
+				r.Error("Commands", "Something went wrong while saving that, check the logs")
+				return
+			}
+			changed = changed || removed
+		}
+	} else {
+		changed, err = settings.SetRule(m.GuildID, name, action, kind, id, true)
+		if err != nil {
+			util.Logger(ctx).Error("Failed to save settings: %v", err)
+			r.Error("Commands", "Something went wrong while saving that, check the logs")
+			return
+		}
+	}
+
+	mention := ruleMention(kind, id)
+	switch {
+	case !changed && action == "remove":
+		r.Embed(util.NewInfoEmbed("Commands", "%s isnt in any rule added for `%s`", mention, name), false)
+	case !changed:
+		r.Embed(util.NewInfoEmbed("Commands", "%s is already on the %s list for `%s`", mention, action, name), false)
+	case action == "remove":
+		r.Embed(util.NewSuccessEmbed("Commands", "Removed %s from the rules for `%s`", mention, name), false)
+	default:
+		r.Embed(util.NewSuccessEmbed("Commands", "Added %s to the %s list for `%s`", mention, action, name), false)
+	}
+}
+
+// ruleTarget works out whether a mention or ID is a channel, a category or a role in the guild
+func ruleTarget(s *discordgo.Session, guildID, target string) (string, string, error) {
+	if id, ok := util.ParseRoleMention(target); ok && strings.HasPrefix(target, "<@&") {
+		if _, err := s.State.Role(guildID, id); err != nil {
+			return "", "", fmt.Errorf("<@&%s> isnt a role in this server", id)
+		}
+		return settings.RuleRole, id, nil
+	}
+
+	id, ok := util.ParseChannelMention(target)
+	if !ok {
+		return "", "", fmt.Errorf("`%s` isnt a channel, category or role, mention one or use its ID", target)
+	}
+	// categories can be mentioned just like channels (<#id>) so we ask the state which one it is
+	if channel, err := s.State.Channel(id); err == nil && channel.GuildID == guildID {
+		if channel.Type == discordgo.ChannelTypeGuildCategory {
+			return settings.RuleCategory, id, nil
+		}
+		return settings.RuleChannel, id, nil
+	}
+	// a plain ID could be a role too
+	if _, err := s.State.Role(guildID, id); err == nil {
+		return settings.RuleRole, id, nil
+	}
+	return "", "", fmt.Errorf("`%s` isnt a channel, category or role in this server", target)
+}
+
+// ruleMention formats a rule target for replies
+func ruleMention(kind, id string) string {
+	switch kind {
+	case settings.RuleRole:
+		return "<@&" + id + ">"
+	case settings.RuleCategory:
+		return "<#" + id + "> (category)"
+	}
+	return "<#" + id + ">"
+}
+
+// rulesEmbed shows a command's allow/deny lists, the ones from the config and the ones added at runtime in this guild
+func rulesEmbed(guildID, name string) *discordgo.MessageEmbed {
+	fromConfig, runtime := settings.Rules(guildID, name)
+	embed := util.NewThemedEmbed(util.ThemeInfo).SetTitle("Rules for " + name)
+
+	listed := false
+	for _, source := range []struct {
+		label string
+		rule  config.CommandRule
+	}{{"config", fromConfig}, {"this server", runtime}} {
+		for _, list := range []struct {
+			title string
+			kind  string
+			ids   []string
+		}{
+			{"Allowed channels", settings.RuleChannel, source.rule.AllowChannels},
+			{"Denied channels", settings.RuleChannel, source.rule.DenyChannels},
+			{"Allowed categories", settings.RuleCategory, source.rule.AllowCategories},
+			{"Denied categories", settings.RuleCategory, source.rule.DenyCategories},
+			{"Allowed roles", settings.RuleRole, source.rule.AllowRoles},
+			{"Denied roles", settings.RuleRole, source.rule.DenyRoles},
+		} {
+			if len(list.ids) == 0 {
+				continue
+			}
+			listed = true
+			mentions := make([]string, len(list.ids))
+			for i, id := range list.ids {
+				mentions[i] = ruleMention(list.kind, id)
+			}
+			embed.AddField(fmt.Sprintf("%s (%s)", list.title, source.label), strings.Join(mentions, ", "))
+		}
+	}
+
+	if !listed {
+		embed.SetDescription("`" + name + "` can be used anywhere by anyone allowed to use it")
+	}
+	return embed.Truncate().MessageEmbed
+}
+
+// commandName finds the real name of a prefix command (by name or alias) or a slash command
+func commandName(name string) (string, bool) {
+	if cmd := Lookup(name); cmd != nil {
+		return cmd.Name, true
+	}
+	for _, cmd := range slashcommands.List() {
+		if strings.EqualFold(cmd.Name, name) {
+			return cmd.Name, true
+		}
+	}
+	return "", false
+}
+
+// disableLevel works out where a change applies from the last argument, nothing means this server (or global in DMs)
+func disableLevel(s *discordgo.Session, m *discordgo.MessageCreate, where string) (string, string, error) {
+	switch strings.ToLower(where) {
+	case "":
+		if m.GuildID == "" {
+			return settings.LevelGlobal, "", nil
+		}
+		return settings.LevelGuild, m.GuildID, nil
+	case "global", "everywhere":
+		return settings.LevelGlobal, "", nil
+	case "server", "guild":
+		if m.GuildID == "" {
+			return "", "", fmt.Errorf("There is no server here, use `global` instead")
+		}
+		return settings.LevelGuild, m.GuildID, nil
+	case "here":
+		return settings.LevelChannel, m.ChannelID, nil
+	}
+
+	channelID, ok := util.ParseChannelMention(where)
+	if !ok {
+		return "", "", fmt.Errorf("`%s` isnt a channel, use `global`, `server`, `here` or mention a channel", where)
+	}
+	// people can only change channels in the server they are in, otherwise an admin in one server could mess with another
+	if channel, err := s.State.Channel(channelID); err != nil || channel.GuildID != m.GuildID {
+		return "", "", fmt.Errorf("<#%s> isnt a channel in this server", channelID)
+	}
+	return settings.LevelChannel, channelID, nil
+}
+
+// stillID returns the ID that goes with a level for the message we are replying to, channelIDs is what DisabledAt was given
+func stillID(name, level string, m *discordgo.MessageCreate, channelIDs []string) string {
+	switch level {
+	case settings.LevelGuild:
+		return m.GuildID
+	case settings.LevelChannel:
+		// in a thread it can be the parent channel keeping it off
+		disabled := settings.DisabledCommands()[name].Channels
+		for _, id := range channelIDs {
+			if slices.Contains(disabled, id) {
+				return id
+			}
+		}
+		return m.ChannelID
+	}
+	return ""
+}
+
+// levelName describes where a change applies for replies
+func levelName(level, id string) string {
+	switch level {
+	case settings.LevelGuild:
+		return "in this server"
+	case settings.LevelChannel:
+		return fmt.Sprintf("in <#%s>", id)
+	}
+	return "everywhere"
+}
+
+// disabledListEmbed lists every command that is disabled somewhere, other guilds and their channels only show as a count
+// so admins of one server never see the channel IDs of another
+func disabledListEmbed(s *discordgo.Session, guildID string) *discordgo.MessageEmbed {
+	disabled := settings.DisabledCommands()
+	embed := util.NewThemedEmbed(util.ThemeInfo).SetTitle("Disabled Commands")
+	if len(disabled) == 0 {
+		return embed.SetDescription("Every command is enabled").MessageEmbed
+	}
+
+	names := make([]string, 0, len(disabled))
+	for name := range disabled {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		d := disabled[name]
+		var where []string
+		if d.Global {
+			where = append(where, "everywhere")
+		}
+
+		otherGuilds := len(d.Guilds)
+		if guildID != "" && slices.Contains(d.Guilds, guildID) {
+			where = append(where, "this server")
+			otherGuilds--
+		}
+		if otherGuilds > 0 {
+			where = append(where, fmt.Sprintf("%d other server(s)", otherGuilds))
+		}
+
+		// channels we cant find in the state count as elsewhere, we cant tell which server they are in
+		otherChannels := 0
+		for _, channelID := range d.Channels {
+			if channel, err := s.State.Channel(channelID); err == nil && guildID != "" && channel.GuildID == guildID {
+				where = append(where, "<#"+channelID+">")
+			} else {
+				otherChannels++
+			}
+		}
+		if otherChannels > 0 {
+			where = append(where, fmt.Sprintf("%d channel(s) elsewhere", otherChannels))
+		}
+		lines = append(lines, fmt.Sprintf("`%s` - %s", name, strings.Join(where, ", ")))
+	}
+	return embed.SetDescription(strings.Join(lines, "\n")).Truncate().MessageEmbed
+}
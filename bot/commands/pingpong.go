@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"context"
 	"template/config"
 	"template/util"
 
@@ -9,21 +10,22 @@ import (
 
 /*
 Parameters:
+  - ctx (context.Context): the command context (deadline, shutdown cancellation, logger and guild settings)
   - s (*discordgo.Session): the active Discord session instance
   - m (*discordgo.MessageCreate): the message that triggered the command
   - args ([]string): a slice of arguments passed to the command
 */
 
-func PingPong(s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+func PingPong(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
 
 	// make an embed using our util package
 	embed := util.NewEmbed().
 		// methods are chainable so we can call them one after another
 		SetTitle("Success").
-		SetThumbnail("https://i.gifer.com/BH2F.gif").                  // add a thumbnail
-		SetColor(255, 255, 255).                                       // SetColor takes RGB values
-		SetDescription("Pong!").                                       // short description
-		SetFooter(config.Config.Brand.Name, config.Config.Brand.Icon). // footer text + icon
+		SetThumbnail("https://i.gifer.com/BH2F.gif"). // add a thumbnail
+		SetNamedColor(util.ThemeSuccess).             // theme names, named colors ("blurple") and hex ("#43b581") all work
+		SetDescription("Pong!").                      // short description
+		ApplyTheme().                                 // default footer (brand name + icon) from the theme config
 
 		// extra fields for demonstration
 		AddField("Field Name", "Field Value").
@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"template/config"
+	"template/settings"
+	"template/util"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+/*
+Parameters:
+  - ctx (context.Context): the command context (deadline, shutdown cancellation, logger and guild settings)
+  - s (*discordgo.Session): the active Discord session instance
+  - m (*discordgo.MessageCreate): the message that triggered the command
+  - args ([]string): the command and its arguments
+
+Usage:
+  - .prefix               shows the prefix for this server
+  - .prefix set <prefix>  changes it (the extra prefixes from the config and mentions keep working)
+  - .prefix reset         goes back to the prefix from the config
+*/
+func Prefix(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	r := util.NewMessageResponder(s, m.Message)
+	guild := util.GuildSettings(ctx)
+
+	if len(args) < 2 {
+		source := "from the config"
+		if guild.CustomPrefix {
+			source = "set for this server"
+		}
+		r.Embed(util.NewInfoEmbed("Prefix", "The prefix here is `%s` (%s)", guild.Prefix, source), false)
+		return
+	}
+
+	if m.GuildID == "" {
+		r.Error("Prefix", "The prefix can only be changed in a server")
+		return
+	}
+
+	var prefix string
+	switch strings.ToLower(args[1]) {
+	case "set":
+		if len(args) < 3 {
+			r.Error("Prefix", "Usage: `%sprefix set <prefix>`", guild.Prefix)
+			return
+		}
+		prefix = args[2]
+		if prefix == config.Config.Prefix {
+			// setting it to the default is the same as a reset, no need to keep it around
+			prefix = ""
+		}
+	case "reset":
+		prefix = ""
+	default:
+		r.Error("Prefix", "Unknown option `%s`, use `set` or `reset`", args[1])
+		return
+	}
+
+	if err := settings.SetPrefix(m.GuildID, prefix); err != nil {
+		r.Error("Prefix", "%v", err)
+		return
+	}
+
+	updated := settings.Resolve(m.GuildID).Prefix
+	r.Embed(util.NewSuccessEmbed("Prefix", "The prefix here is now `%s`, try `%shelp`", updated, updated), false)
+}
@@ -1,14 +1,16 @@
 package commands
 
 import (
-	"os"
+	"context"
+	"fmt"
 	"strings"
 	"sync"
 	"template/config"
+	"template/logging"
+	"template/util"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/fatih/color"
-	"github.com/yourpov/logrite"
 )
 
 // Command struct is the structure for a prefix command
@@ -17,13 +19,14 @@ type Command struct {
 	Alias       []string
 	Description string
 	AdminOnly   bool
-	Execute     func(s *discordgo.Session, m *discordgo.MessageCreate, args []string)
+	Execute     func(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, args []string)
 }
 
 // Commands map
 var (
 	Commands = make(map[string]*Command)
-	lock     sync.Mutex
+	index    = make(map[string]*Command) // every name and alias in lower case, so lookups dont have to scan
+	lock     sync.RWMutex
 	cmds     = []Command{{
 		Name:        "help",               // name of command
 		Alias:       []string{"commands"}, // aliases of the command
@@ -42,82 +45,169 @@ var (
 		Description: "ping pong command",
 		AdminOnly:   true,
 		Execute:     PingPong,
+	}, {
+		Name:        "presence",
+		Alias:       []string{"status"},
+		Description: "Show or change the bot's presence",
+		AdminOnly:   true,
+		Execute:     Presence,
+	}, {
+		Name:        "command",
+		Alias:       []string{"cmd"},
+		Description: "Enable or disable commands globally, per server or per channel",
+		AdminOnly:   true,
+		Execute:     ManageCommands,
+	}, {
+		Name:        "prefix",
+		Alias:       []string{"setprefix"},
+		Description: "Show or change the prefix for this server",
+		AdminOnly:   true,
+		Execute:     Prefix,
+	}, {
+		Name:        "version",
+		Alias:       []string{"build"},
+		Description: "Show which build is running",
+		AdminOnly:   false,
+		Execute:     Version,
 	},
 	}
 )
 
-// Load loads all commands into the Commands map
-func Load() {
+// Load loads all commands into the Commands map and the lookup index
+// a name or alias used twice would quietly shadow another command so we refuse to start instead
+func Load() error {
+	if collisions := Check(); len(collisions) > 0 {
+		for _, c := range collisions {
+			logging.Error("Prefix command collision: %s", c)
+		}
+		return fmt.Errorf("%d prefix command name/alias collision(s), every name and alias has to be unique", len(collisions))
+	}
+
 	for _, cmd := range cmds {
 		newCommand(cmd)
-		//logrite.Success("Registered Prefix Command: %s ", cmd.Name)
-		logrite.Custom("⚙️ ", "COMMAND", "Registered prefix command: %s", color.FgWhite, color.BgGreen, cmd.Name)
+		//logging.Success("Registered Prefix Command: %s ", cmd.Name)
+		logging.Custom("⚙️ ", "COMMAND", "Registered prefix command: %s", color.FgWhite, color.BgGreen, cmd.Name)
+	}
+	return nil
+}
+
+// Check returns a line for every name or alias (ignoring case) that more than one command claims,
+// or that a command lists twice, so check-config and Load can report all of them at once
+func Check() []string {
+	// what each lower case name is used as, in the order we came across them
+	uses := make(map[string][]string)
+	var order []string
+	claim := func(key, use string) {
+		key = strings.ToLower(key)
+		if _, ok := uses[key]; !ok {
+			order = append(order, key)
+		}
+		uses[key] = append(uses[key], use)
+	}
+
+	for _, cmd := range cmds {
+		claim(cmd.Name, fmt.Sprintf("the name of %s", cmd.Name))
+		for _, alias := range cmd.Alias {
+			claim(alias, fmt.Sprintf("an alias of %s", cmd.Name))
+		}
+	}
 
+	var collisions []string
+	for _, key := range order {
+		if len(uses[key]) > 1 {
+			collisions = append(collisions, fmt.Sprintf("%q is %s", key, strings.Join(uses[key], " and ")))
+		}
 	}
+	return collisions
 }
 
-// newCommand adds a new command to the map
+// List returns every prefix command we have in the order they are declared, without needing Load
+func List() []Command {
+	return append([]Command(nil), cmds...)
+}
+
+// newCommand adds a new command to the map and its name and aliases to the index
 func newCommand(c Command) {
 	lock.Lock()
 	defer lock.Unlock()
 
-	if existing, ok := Commands[c.Name]; ok {
-		// here we check for conflicting command names
-		logrite.Error("Conflicting command names: '%s' already exists", c.Name)
-		logrite.Error("Existing command: %+v", existing)
-		logrite.Error("New command: %+v", c)
-		os.Exit(1)
+	Commands[c.Name] = &c
+	index[strings.ToLower(c.Name)] = &c
+	for _, alias := range c.Alias {
+		index[strings.ToLower(alias)] = &c
 	}
+}
 
-	Commands[c.Name] = &c
+// Lookup finds a command by name or alias in any case, without checking who is asking
+func Lookup(name string) *Command {
+	lock.RLock()
+	defer lock.RUnlock()
+	return index[strings.ToLower(name)]
 }
 
 // GetCommand retrieves a command by name or alias, and checks if the user is authorized to use it
 func GetCommand(name string, m *discordgo.MessageCreate) (bool, *Command) {
-	lock.Lock()
-	/* some people get confused with this but i do this to avoid deadlocks
-	   i lock the mutex at the start of the function and use defer to unlock it when the function exits
-	   this way it doesn't matter how many return statements we have since unlock will always run before the function actually returns */
-	defer lock.Unlock()
+	cmd := Lookup(name)
+	if cmd == nil {
+		// if we reach here the command was not found
+		return false, nil
+	}
+	// admin only commands also need the user to be in the authorized list
+	return Authorized(cmd, m.Author.ID), cmd
+}
 
-	cmd, ok := Commands[name]
-	// we want to check if the command exists first
-	if ok && cmd != nil {
-		if cmd.AdminOnly {
-			// we also want to check if the user is authorized to use it
-			for _, v := range config.Config.AuthenticatedIds {
-				if strings.EqualFold(m.Author.ID, v) {
-					// the user is in the authorized list for admin-only commands
-					return true, cmd
-				}
-			}
-			// we return false when the user's ID is not authorized
-			return false, cmd
+// Authorized reports whether a user can run a command, admin only ones need the user in authenticated_ids
+func Authorized(cmd *Command, userID string) bool {
+	if !cmd.AdminOnly {
+		return true
+	}
+	for _, v := range config.Config.AuthenticatedIds {
+		if strings.EqualFold(userID, v) {
+			return true
 		}
-		// this time we return true because all users are automatically authorized to use it
-		return true, cmd
 	}
+	return false
+}
 
-	// we want to check for command aliases as well
+// Suggest returns up to max command names that look like what was typed, closest first
+// only commands allowed says yes to are suggested so nobody gets pointed at a command they cant run
+func Suggest(typed string, max int, allowed func(cmd *Command) bool) []string {
+	// allowed can look at settings and such so we dont call it while holding our lock
+	lock.RLock()
+	all := make([]*Command, 0, len(Commands))
 	for _, cmd := range Commands {
-		for _, alias := range cmd.Alias {
-			if strings.EqualFold(name, alias) {
-				if cmd.AdminOnly {
-					// we also want to check if the user is authorized to use it
-					for _, v := range config.Config.AuthenticatedIds {
-						if strings.EqualFold(m.Author.ID, v) {
-							// user is in the authorized list for admin-only commands
-							return true, cmd
-						}
-					}
-					// we return false when the user's ID is not authorized
-					return false, cmd
-				}
-				// this time we return true because all users are automatically authorized to use it
-				return true, cmd
+		all = append(all, cmd)
+	}
+	lock.RUnlock()
+
+	// names and aliases both count, a typo of an alias still suggests the command
+	owner := make(map[string]*Command)
+	var candidates []string
+	for _, cmd := range all {
+		if !allowed(cmd) {
+			continue
+		}
+		for _, name := range append([]string{cmd.Name}, cmd.Alias...) {
+			key := strings.ToLower(name)
+			if _, ok := owner[key]; !ok {
+				owner[key] = cmd
+				candidates = append(candidates, key)
 			}
 		}
 	}
-	// if we reach here the command was not found
-	return false, nil
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, candidate := range util.Closest(typed, candidates, len(candidates)) {
+		name := owner[candidate].Name
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+		if len(names) == max {
+			break
+		}
+	}
+	return names
 }
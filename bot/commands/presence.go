@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"template/presence"
+	"template/util"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+/*
+Parameters:
+  - ctx (context.Context): the command context (deadline, shutdown cancellation, logger and guild settings)
+  - s (*discordgo.Session): the active Discord session instance
+  - m (*discordgo.MessageCreate): the message that triggered the command
+  - args ([]string): the command and its arguments
+
+Usage:
+  - .presence                       shows what the presence is set to
+  - .presence set <type> <text>     pins an activity (playing, watching, listening, competing or custom)
+  - .presence status <status>       sets the status (online, idle, dnd or invisible)
+  - .presence reset                 goes back to the rotation from the config
+*/
+func Presence(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	r := util.NewMessageResponder(s, m.Message)
+	prefix := util.GuildSettings(ctx).Prefix
+
+	if len(args) < 2 {
+		activity, status, pinned := presence.Current()
+		mode := "rotating from the config"
+		if pinned {
+			mode = "pinned, use `" + prefix + "presence reset` to go back to the rotation"
+		}
+
+		text := activity.Text
+		if text == "" {
+			text = "none"
+		}
+
+		embed := util.NewThemedEmbed(util.ThemeInfo).
+			SetTitle("Presence").
+			AddField("Status", status).
+			AddField("Activity", fmt.Sprintf("%s `%s`", activity.Type, text)).
+			AddField("Mode", mode)
+		r.Embed(embed.MessageEmbed, false)
+		return
+	}
+
+	var err error
+	switch strings.ToLower(args[1]) {
+	case "set":
+		if len(args) < 4 {
+			r.Error("Presence", "Usage: `%spresence set <%s> <text>`", prefix, strings.Join(presence.ActivityTypes(), "|"))
+			return
+		}
+		err = presence.Set(args[2], strings.Join(args[3:], " "), "")
+	case "status":
+		if len(args) < 3 {
+			r.Error("Presence", "Usage: `%spresence status <online|idle|dnd|invisible>`", prefix)
+			return
+		}
+		err = presence.Set("", "", args[2])
+	case "reset":
+		err = presence.Reset()
+	default:
+		r.Error("Presence", "Unknown option `%s`, use `set`, `status` or `reset`", args[1])
+		return
+	}
+
+	if err != nil {
+		r.Error("Presence", "%v", err)
+		return
+	}
+	r.Embed(util.NewSuccessEmbed("Presence", "Presence updated on every shard"), false)
+}
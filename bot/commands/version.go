@@ -0,0 +1,16 @@
+package commands
+
+import (
+	"context"
+	"template/buildinfo"
+	"template/util"
+	"template/util/templates"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Version shows which build is running, the layout lives in config/templates/version.json and is shared with /version
+func Version(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, args []string) {
+	data := templates.NewData(s, m.GuildID, m.Author).With("build", buildinfo.Get())
+	util.NewMessageResponder(s, m.Message).Embed(templates.Render("version", data).MessageEmbed, false)
+}
@@ -0,0 +1,151 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"template/audit"
+	"template/config"
+	"template/lifecycle"
+	"template/logging"
+	"template/monitor"
+	"template/settings"
+	"template/util"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// invocation describes a single use of a command, both dispatchers fill one in
+// so metrics and logs get the same details no matter how the command was run
+type invocation struct {
+	session   *discordgo.Session
+	id        string // the message or interaction ID, it doubles as the invocation ID in logs and the audit log
+	name      string
+	kind      string // prefix or slash
+	userID    string
+	username  string
+	guildID   string
+	channelID string
+	args      []string
+	admin     bool
+	respondBy time.Time // when the interaction response window closes, zero for prefix commands
+}
+
+// run executes a command handler and records how it went
+// it also recovers panics so one broken command doesnt take the whole bot down
+func (inv invocation) run(execute func(ctx context.Context)) {
+	ctx, cancel := inv.context()
+	defer cancel()
+
+	start := time.Now()
+	outcome := monitor.OutcomeSuccess
+
+	defer func() {
+		if r := recover(); r != nil {
+			outcome = monitor.OutcomeError
+			util.Logger(ctx).Error("Command '%s' panicked: %v", inv.name, r)
+		}
+		inv.record(outcome, time.Since(start))
+	}()
+
+	execute(ctx)
+}
+
+// context builds the context a command runs with, it carries the invocation details, a logger,
+// the guild settings and gets canceled on timeout or when shutdown stops waiting for commands
+func (inv invocation) context() (context.Context, context.CancelFunc) {
+	logger := logging.With(logging.Fields{
+		"invocation_id": inv.id,
+		"command":       inv.name,
+		"type":          inv.kind,
+		"user_id":       inv.userID,
+		"guild_id":      inv.guildID,
+		"channel_id":    inv.channelID,
+	})
+
+	return util.NewCommandContext(lifecycle.Context(), &util.Invocation{
+		ID:        inv.id,
+		Command:   inv.name,
+		Type:      inv.kind,
+		RespondBy: inv.respondBy,
+		Logger:    logger,
+		Guild:     settings.Resolve(inv.guildID),
+	}, time.Duration(config.Config.CommandTimeout)*time.Second)
+}
+
+// record reports a command execution (or rejection) to our metrics, logs and the audit log
+// unknown commands only go to metrics and logs, anything starting with the prefix lands there (other bots, "...")
+// and the audit log is for commands people actually ran, not whatever they typed
+func (inv invocation) record(outcome string, took time.Duration) {
+	if outcome != monitor.OutcomeNotFound {
+		inv.audit(outcome)
+	}
+
+	monitor.ObserveCommand(inv.name, inv.kind, outcome, took)
+	logging.CommandExecuted(logging.CommandEntry{
+		InvocationID: inv.id,
+		Name:         inv.name,
+		Type:         inv.kind,
+		UserID:       inv.userID,
+		GuildID:      inv.guildID,
+		ChannelID:    inv.channelID,
+		Duration:     took,
+		Outcome:      outcome,
+	})
+}
+
+// audit writes the invocation to the audit log
+func (inv invocation) audit(outcome string) {
+	audit.Record(inv.session, audit.Entry{
+		ID:        inv.id,
+		Command:   inv.name,
+		Type:      inv.kind,
+		UserID:    inv.userID,
+		Username:  inv.username,
+		GuildID:   inv.guildID,
+		ChannelID: inv.channelID,
+		Args:      inv.args,
+		Admin:     inv.admin,
+		Result:    outcome,
+	})
+}
+
+// access works out where a command is being used and by whom so the allow/deny lists can be checked
+func access(s *discordgo.Session, guildID, channelID string, member *discordgo.Member) settings.Access {
+	a := settings.Access{GuildID: guildID, ChannelIDs: []string{channelID}}
+	if member != nil {
+		a.Roles = member.Roles
+	}
+
+	channel, err := s.State.Channel(channelID)
+	if err != nil {
+		// DMs and channels we dont have cached just get checked by their ID
+		return a
+	}
+	if channel.IsThread() {
+		// a thread counts as the channel it was started in, so rules for #general cover its threads too
+		a.ChannelIDs = append(a.ChannelIDs, channel.ParentID)
+		if parent, err := s.State.Channel(channel.ParentID); err == nil {
+			a.CategoryID = parent.ParentID
+		}
+		return a
+	}
+	a.CategoryID = channel.ParentID
+	return a
+}
+
+// optionArgs flattens slash command options into "name=value" strings for the audit log
+func optionArgs(options []*discordgo.ApplicationCommandInteractionDataOption) []string {
+	var args []string
+	for _, opt := range options {
+		switch opt.Type {
+		case discordgo.ApplicationCommandOptionSubCommand, discordgo.ApplicationCommandOptionSubCommandGroup:
+			// subcommands just show up by name with their own options after them
+			args = append(args, opt.Name)
+			args = append(args, optionArgs(opt.Options)...)
+		default:
+			args = append(args, fmt.Sprintf("%s=%v", opt.Name, opt.Value))
+		}
+	}
+	return args
+}
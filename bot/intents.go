@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"template/config"
+	"template/logging"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// intentNames maps the names used in config.json (intents.add / intents.remove) to their intents
+var intentNames = map[string]discordgo.Intent{
+	"guilds":                    discordgo.IntentsGuilds,
+	"guild_members":             discordgo.IntentsGuildMembers,
+	"guild_bans":                discordgo.IntentsGuildBans,
+	"guild_emojis":              discordgo.IntentsGuildEmojis,
+	"guild_integrations":        discordgo.IntentsGuildIntegrations,
+	"guild_webhooks":            discordgo.IntentsGuildWebhooks,
+	"guild_invites":             discordgo.IntentsGuildInvites,
+	"guild_voice_states":        discordgo.IntentsGuildVoiceStates,
+	"guild_presences":           discordgo.IntentsGuildPresences,
+	"guild_messages":            discordgo.IntentsGuildMessages,
+	"guild_message_reactions":   discordgo.IntentsGuildMessageReactions,
+	"guild_message_typing":      discordgo.IntentsGuildMessageTyping,
+	"direct_messages":           discordgo.IntentsDirectMessages,
+	"direct_message_reactions":  discordgo.IntentsDirectMessageReactions,
+	"direct_message_typing":     discordgo.IntentsDirectMessageTyping,
+	"message_content":           discordgo.IntentsMessageContent,
+	"guild_scheduled_events":    discordgo.IntentsGuildScheduledEvents,
+	"auto_moderation_config":    discordgo.IntentAutoModerationConfiguration,
+	"auto_moderation_execution": discordgo.IntentAutoModerationExecution,
+}
+
+// privilegedIntents have to be switched on in the Developer Portal or Discord closes the connection (4014)
+var privilegedIntents = []struct {
+	intent discordgo.Intent
+	name   string // what the toggle is called in the Developer Portal
+	reason string // why a feature needs it, shown when wanted says that feature is on
+	wanted func() bool
+}{
+	{discordgo.IntentsMessageContent, "Message Content Intent", "prefix commands need to read messages", func() bool { return config.Config.PrefixEnabled }},
+	{discordgo.IntentsGuildMembers, "Server Members Intent", "intents.members is enabled", func() bool { return config.Config.Intents.Members }},
+	{discordgo.IntentsGuildPresences, "Presence Intent", "intents.presences is enabled", func() bool { return config.Config.Intents.Presences }},
+}
+
+// intents works out which gateway intents we need from the features that are turned on
+// we only ask for what we use, privileged intents especially since they need approval once the bot is verified
+func intents() discordgo.Intent {
+	c := config.Config.Intents
+
+	// guilds keeps the state cache (guilds, channels, roles) filled, pretty much everything relies on it
+	i := discordgo.IntentsGuilds
+
+	if config.Config.PrefixEnabled {
+		// we need these intents to tell if a user is using a prefix command
+		i |= discordgo.IntentsGuildMessages | discordgo.IntentsMessageContent
+		if c.DirectMessages {
+			i |= discordgo.IntentsDirectMessages
+		}
+	}
+	if c.Members {
+		i |= discordgo.IntentsGuildMembers
+	}
+	if c.Presences {
+		i |= discordgo.IntentsGuildPresences
+	}
+	if c.Reactions {
+		i |= discordgo.IntentsGuildMessageReactions
+		if c.DirectMessages {
+			i |= discordgo.IntentsDirectMessageReactions
+		}
+	}
+	if c.VoiceStates {
+		i |= discordgo.IntentsGuildVoiceStates
+	}
+
+	// anything the features above dont cover can be added (or taken away) by name
+	for _, name := range c.Add {
+		if intent, ok := intentNames[strings.ToLower(name)]; ok {
+			i |= intent
+		} else {
+			logging.Warn("Unknown intent '%s' in intents.add", name)
+		}
+	}
+	for _, name := range c.Remove {
+		if intent, ok := intentNames[strings.ToLower(name)]; ok {
+			i &^= intent
+		} else {
+			logging.Warn("Unknown intent '%s' in intents.remove", name)
+		}
+	}
+
+	return i
+}
+
+// UnknownIntents returns the names in intents.add and intents.remove that we dont know, intents() skips them with a warning
+func UnknownIntents() []string {
+	var unknown []string
+	for _, list := range []struct {
+		key   string
+		names []string
+	}{{"intents.add", config.Config.Intents.Add}, {"intents.remove", config.Config.Intents.Remove}} {
+		for _, name := range list.names {
+			if _, ok := intentNames[strings.ToLower(name)]; !ok {
+				unknown = append(unknown, fmt.Sprintf("%s has unknown intent %q", list.key, name))
+			}
+		}
+	}
+	return unknown
+}
+
+// warnPrivileged tells whoever is running the bot which privileged intents we are asking for, why,
+// and where to turn them on, otherwise the only hint they get is Discord closing the connection
+func warnPrivileged(i discordgo.Intent) {
+	for _, p := range privilegedIntents {
+		if i&p.intent == 0 {
+			continue
+		}
+		logging.Warn("Requesting the privileged %s (%s), enable it in the Developer Portal under Bot > Privileged Gateway Intents", p.name, privilegedSource(p.intent, p.reason, p.wanted()))
+	}
+
+	if config.Config.PrefixEnabled && i&discordgo.IntentsMessageContent == 0 {
+		logging.Warn("Prefix commands are enabled but message_content was removed, prefix commands wont see what users type")
+	}
+}
+
+// privilegedSource says what asked for a privileged intent, the feature that needs it, intents.add or both
+func privilegedSource(intent discordgo.Intent, reason string, wanted bool) string {
+	var sources []string
+	if wanted {
+		sources = append(sources, reason)
+	}
+	for _, name := range config.Config.Intents.Add {
+		if intentNames[strings.ToLower(name)] == intent {
+			sources = append(sources, fmt.Sprintf("%q is in intents.add", name))
+			break
+		}
+	}
+	return strings.Join(sources, " and ")
+}
@@ -0,0 +1,56 @@
+package bot
+
+import (
+	"strings"
+	"template/config"
+	"template/settings"
+	"unicode"
+)
+
+// mentionPrefixes are the two ways a mention of the bot can look, <@!id> is what older clients send for nicknamed members
+func mentionPrefixes(botID string) []string {
+	return []string{"<@" + botID + ">", "<@!" + botID + ">"}
+}
+
+// isBareMention reports whether a message is nothing but a mention of the bot
+func isBareMention(content, botID string) bool {
+	content = strings.TrimSpace(content)
+	for _, mention := range mentionPrefixes(botID) {
+		if content == mention {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPrefix checks a message against every prefix that works in the guild (and the bot mention when that is on),
+// it returns what comes after the prefix with any whitespace between them trimmed off
+// the longest prefix wins so "!!" is picked over "!" when both are set
+func matchPrefix(content string, guild settings.Guild, botID string) (string, bool) {
+	prefixes := guild.Prefixes
+	if config.Config.MentionPrefix {
+		prefixes = append(append([]string(nil), prefixes...), mentionPrefixes(botID)...)
+	}
+
+	best := ""
+	for _, prefix := range prefixes {
+		if prefix == "" || len(prefix) <= len(best) || len(content) < len(prefix) {
+			continue
+		}
+		head := content[:len(prefix)]
+		if head == prefix || (config.Config.PrefixCaseInsensitive && strings.EqualFold(head, prefix)) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return "", false
+	}
+
+	// TrimLeftFunc instead of a fixed cutset so things like non-breaking spaces count too, Fields splits on those
+	rest := strings.TrimLeftFunc(content[len(best):], unicode.IsSpace)
+	if rest == "" {
+		// a prefix on its own isnt a command
+		return "", false
+	}
+	return rest, true
+}
@@ -0,0 +1,130 @@
+package slashcommands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"template/audit"
+	"template/util"
+	"template/util/timeutil"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// auditPerPage is how many audit entries we show on each page
+const auditPerPage = 10
+
+// auditMaxEntries is how many of the newest matching entries /audit shows, nobody pages through more than this
+// and it keeps a search of a big log from holding all of it in memory
+const auditMaxEntries = 500
+
+// auditOptions are the filters /audit accepts, all of them are optional
+var auditOptions = []*discordgo.ApplicationCommandOption{
+	{
+		Type:        discordgo.ApplicationCommandOptionUser,
+		Name:        "user",
+		Description: "Only show commands run by this user",
+	},
+	{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "command",
+		Description: "Only show this command",
+	},
+	{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "since",
+		Description: "How far back to look (e.g. 30m, 7d, yesterday, last monday or 2025-01-31)",
+	},
+	{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "until",
+		Description: "Only show entries before this (e.g. 1h, 2d or 2025-01-31)",
+	},
+}
+
+// Audit lets admins search the command audit log
+func Audit(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	r := util.NewInteractionResponder(s, i.Interaction)
+
+	// we build the filter from whatever options were passed in
+	var filter audit.Filter
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "user":
+			filter.UserID = opt.UserValue(nil).ID
+		case "command":
+			filter.Command = opt.StringValue()
+		case "since", "until":
+			t, err := parseAuditTime(opt.StringValue(), time.Now())
+			if err != nil {
+				r.Error("Invalid Time", "`%s` isnt a time I understand, try something like `24h`, `7d`, `yesterday` or `2025-01-31`", opt.StringValue())
+				return
+			}
+			if opt.Name == "since" {
+				filter.Since = t
+			} else {
+				filter.Until = t
+			}
+		}
+	}
+
+	// reading the log takes as long as the log is big so we defer before touching it,
+	// otherwise a big one runs past the response window and the user just gets "Unknown interaction"
+	if err := r.Defer(true); err != nil {
+		util.Logger(ctx).Error("Failed to defer /audit: %v", err)
+		return
+	}
+
+	filter.Limit = auditMaxEntries
+	entries, err := audit.Query(ctx, filter)
+	if err != nil {
+		util.Logger(ctx).Error("Failed to query the audit log: %v", err)
+		r.Error("Audit Log", "Failed to read the audit log: %v", err)
+		return
+	}
+	if len(entries) == 0 {
+		r.Embed(util.NewInfoEmbed("Audit Log", "No entries match those filters"), true)
+		return
+	}
+
+	title := fmt.Sprintf("Audit Log (%d entries)", len(entries))
+	if len(entries) == auditMaxEntries {
+		title = fmt.Sprintf("Audit Log (newest %d entries)", auditMaxEntries)
+	}
+
+	pages := (len(entries) + auditPerPage - 1) / auditPerPage
+	paginator := util.NewPaginatorFunc(pages, func(page int) *discordgo.MessageEmbed {
+		start := page * auditPerPage
+		end := start + auditPerPage
+		if end > len(entries) {
+			end = len(entries)
+		}
+
+		lines := make([]string, 0, end-start)
+		for _, e := range entries[start:end] {
+			lines = append(lines, audit.Format(e))
+		}
+
+		embed := util.NewThemedEmbed(util.ThemeInfo).
+			SetTitle(title).
+			SetDescription(strings.Join(lines, "\n"))
+		embed.AppendFooter(fmt.Sprintf("Page %d/%d", page+1, pages))
+		return embed.MessageEmbed
+	})
+
+	// the audit log is for admins only so we keep it ephemeral
+	if err := paginator.SetOwner(util.InteractionUserID(i.Interaction)).RespondDeferred(s, i.Interaction, true); err != nil {
+		util.Logger(ctx).Error("Failed to send the audit log: %v", err)
+	}
+}
+
+// parseAuditTime reads a point in time for the audit filters, bare durations like 30m, 24h and 7d are counted
+// back from now (since thats the only direction an audit log goes) and anything else goes to the time parser
+// so "yesterday", "last monday 9am" and 2025-01-31 all work
+func parseAuditTime(s string, now time.Time) (time.Time, error) {
+	if d, err := timeutil.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	return timeutil.Parse(s, now)
+}
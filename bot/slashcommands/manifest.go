@@ -0,0 +1,87 @@
+package slashcommands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// MaxCommands is how many commands of each type discord lets an application register
+const MaxCommands = 100
+
+// Manifest returns the slash commands scoped to a guild (or global for an empty guildID) as the JSON array
+// discord's bulk overwrite endpoint takes, so it can be committed and reviewed like code
+func Manifest(guildID string) ([]byte, error) {
+	defs := DefinitionsFor(guildID)
+	if defs == nil {
+		// an empty manifest still has to be an array or register would choke on it
+		defs = []*discordgo.ApplicationCommand{}
+	}
+	data, err := json.MarshalIndent(defs, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// ReadManifest reads a manifest written by Manifest (or by hand) and makes sure discord would accept it
+func ReadManifest(path string) ([]*discordgo.ApplicationCommand, error) {
+	f, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var defs []*discordgo.ApplicationCommand
+	if err = json.Unmarshal(f, &defs); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	// names only have to be unique per type, a user and a chat command can share one
+	seen := make(map[string]bool)
+	counts := make(map[discordgo.ApplicationCommandType]int)
+	for i, def := range defs {
+		if def == nil || def.Name == "" {
+			return nil, fmt.Errorf("%s: command %d has no name", path, i)
+		}
+		kind := def.Type
+		if kind == 0 {
+			kind = discordgo.ChatApplicationCommand
+		}
+		if kind == discordgo.ChatApplicationCommand && def.Description == "" {
+			return nil, fmt.Errorf("%s: /%s has no description, chat commands need one", path, def.Name)
+		}
+
+		key := fmt.Sprintf("%d:%s", kind, def.Name)
+		if seen[key] {
+			return nil, fmt.Errorf("%s: %s is in there twice", path, def.Name)
+		}
+		seen[key] = true
+
+		if counts[kind]++; counts[kind] > MaxCommands {
+			return nil, fmt.Errorf("%s: discord only allows %d commands of each type", path, MaxCommands)
+		}
+	}
+	return defs, nil
+}
+
+// Overwrite replaces the commands registered on Discord with defs in a single request and returns what changed
+// with dryRun set nothing is sent and the changes are just returned
+func Overwrite(s *discordgo.Session, appID, guildID string, defs []*discordgo.ApplicationCommand, dryRun bool) ([]Change, error) {
+	remote, err := Remote(s, appID, guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := Diff(defs, remote)
+	if dryRun {
+		return changes, nil
+	}
+
+	// bulk overwrite keeps the IDs of commands that already exist so permissions set on them in a server stick around
+	if _, err = s.ApplicationCommandBulkOverwrite(appID, guildID, defs); err != nil {
+		return changes, err
+	}
+	return changes, nil
+}
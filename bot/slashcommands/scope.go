@@ -0,0 +1,143 @@
+package slashcommands
+
+import (
+	"sort"
+	"template/config"
+	"template/logging"
+	"template/settings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// scope targets, anything else in a Scope is a guild ID
+const (
+	// ScopeGlobal registers the command for every guild (and DMs), global commands can take a moment to show up
+	ScopeGlobal = "global"
+	// ScopeDev registers the command in dev_guild_id (or guild_id) so it can be tried out before it goes global
+	ScopeDev = "dev"
+)
+
+// Scope is where a command is registered, a list of ScopeGlobal, ScopeDev or guild IDs
+// an empty scope uses guild_id from the config, or global when that is empty, like every command did before scopes
+type Scope []string
+
+// Guilds returns a scope for a list of guilds
+func Guilds(ids ...string) Scope {
+	return Scope(ids)
+}
+
+// scopeOf returns the scope of a command, command_scopes in the config wins so a command can be promoted without a rebuild
+func scopeOf(c Command) Scope {
+	if override, ok := config.Config.CommandScopes[c.Name]; ok {
+		return Scope(override)
+	}
+	return c.Scope
+}
+
+// guildTargets resolves a command's scope into the guild IDs it is registered in, an empty string means global
+func guildTargets(c Command) []string {
+	scope := scopeOf(c)
+	if len(scope) == 0 {
+		return []string{config.Config.GuildID}
+	}
+
+	var targets []string
+	seen := make(map[string]bool)
+	for _, target := range scope {
+		guildID := target
+		switch target {
+		case ScopeGlobal:
+			guildID = ""
+		case ScopeDev:
+			guildID = devGuildID()
+			if guildID == "" {
+				// a dev command going global by accident is exactly what the dev scope is there to stop
+				logging.Warn("/%s is scoped to the dev guild but neither dev_guild_id nor guild_id is set, skipping it", c.Name)
+				continue
+			}
+		}
+		if !seen[guildID] {
+			seen[guildID] = true
+			targets = append(targets, guildID)
+		}
+	}
+	return targets
+}
+
+// scopeName describes a guild target for logs and the CLI
+func scopeName(guildID string) string {
+	if guildID == "" {
+		return "global"
+	}
+	return "guild " + guildID
+}
+
+// ScopeName describes a guild target for logs and the CLI, an empty guildID is global
+func ScopeName(guildID string) string {
+	return scopeName(guildID)
+}
+
+// ScopeTargets returns the guild IDs a command is registered in after config overrides, an empty string means global
+func ScopeTargets(c Command) []string {
+	return guildTargets(c)
+}
+
+// devGuildID returns the guild dev scoped commands go to
+func devGuildID() string {
+	if config.Config.DevGuildID != "" {
+		return config.Config.DevGuildID
+	}
+	return config.Config.GuildID
+}
+
+// Targets returns every guild we might have registered commands in (plus "" for global), sorted with global first
+// a target with no commands left in it is still returned so syncing can clean up after a command moves scopes,
+// that includes the places we registered in last time (a guild dropped from command_scopes)
+func Targets() []string {
+	seen := make(map[string]bool)
+	for _, guildID := range append(configTargets(), settings.SlashTargets()...) {
+		seen[guildID] = true
+	}
+	return sortedTargets(seen)
+}
+
+// configTargets returns the places the config and our scopes put commands in right now
+func configTargets() []string {
+	seen := map[string]bool{"": true}
+	if config.Config.GuildID != "" {
+		seen[config.Config.GuildID] = true
+	}
+	if id := devGuildID(); id != "" {
+		seen[id] = true
+	}
+	for _, cmd := range cmds {
+		for _, guildID := range guildTargets(cmd) {
+			seen[guildID] = true
+		}
+	}
+	return sortedTargets(seen)
+}
+
+// sortedTargets turns a set of targets into a sorted list, global ("") sorts first
+func sortedTargets(seen map[string]bool) []string {
+	targets := make([]string, 0, len(seen))
+	for guildID := range seen {
+		targets = append(targets, guildID)
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// DefinitionsFor returns the definitions of the commands registered in a guild, an empty guildID gives the global ones
+func DefinitionsFor(guildID string) []*discordgo.ApplicationCommand {
+	var defs []*discordgo.ApplicationCommand
+	for _, cmd := range cmds {
+		for _, target := range guildTargets(cmd) {
+			if target == guildID {
+				defs = append(defs, cmd.definition())
+				break
+			}
+		}
+	}
+	return defs
+}
@@ -1,14 +1,17 @@
 package slashcommands
 
 import (
-	"os"
+	"context"
+	"fmt"
 	"strings"
 	"sync"
 	"template/config"
+	"template/logging"
+	"template/settings"
+	"template/util"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/fatih/color"
-	"github.com/yourpov/logrite"
 )
 
 // RegisteredCommands stores the actual registered commands from Discord
@@ -25,7 +28,17 @@ type Command struct {
 	Type        discordgo.ApplicationCommandType
 	Options     []*discordgo.ApplicationCommandOption
 	Admin       bool
-	Execute     func(s *discordgo.Session, i *discordgo.InteractionCreate)
+	// Scope is where the command is registered (ScopeGlobal, ScopeDev or guild IDs), empty uses guild_id like before
+	Scope Scope
+	// Permissions are the default member permissions needed to see the command (e.g. discordgo.PermissionManageMessages),
+	// nil lets everyone see it, server admins can still change this in their integration settings
+	Permissions *int64
+	// Contexts is where the command can be used (guilds, the bot's DMs, private channels), nil leaves it to Discord's default
+	Contexts []discordgo.InteractionContextType
+	// NameLocalizations and DescriptionLocalizations translate the command for users with another client language
+	NameLocalizations        map[discordgo.Locale]string
+	DescriptionLocalizations map[discordgo.Locale]string
+	Execute                  func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate)
 }
 
 // Commands map for slash commands
@@ -44,80 +57,146 @@ var cmds = []Command{
 		Execute:     PingPong,                         // function to execute
 	}, {
 		Name:        "uptime",
-		Description: "Show bot uptime",
+		Description: "Show bot uptime, status and system information",
 		Type:        discordgo.ChatApplicationCommand,
 		Admin:       false,
 		Execute:     Uptime,
+	}, {
+		Name:        "audit",
+		Description: "Search the command audit log",
+		Type:        discordgo.ChatApplicationCommand,
+		Options:     auditOptions,
+		Admin:       true,
+		Execute:     Audit,
+	}, {
+		Name:        "version",
+		Description: "Show which build is running",
+		Type:        discordgo.ChatApplicationCommand,
+		Admin:       false,
+		Execute:     Version,
 	},
 }
 
-// newCommand registeres a command in cmds
-func newCommand(c Command) {
-	if _, ok := Commands[c.Name]; ok {
-		logrite.Error("Conflicting Slash Commands")
-		os.Exit(1)
+// List returns every slash command we have in the order they are declared, without needing a session
+func List() []Command {
+	return append([]Command(nil), cmds...)
+}
+
+// Prepare checks our slash commands for collisions and fills Commands so interactions can be looked up
+// it runs before we connect so a collision stops the bot before anything gets registered with Discord
+func Prepare() error {
+	if collisions := Check(); len(collisions) > 0 {
+		for _, c := range collisions {
+			logging.Error("Slash command collision: %s", c)
+		}
+		return fmt.Errorf("%d slash command name collision(s), every name has to be unique", len(collisions))
+	}
+
+	for _, v := range cmds {
+		newCommand(v)
+	}
+	return nil
+}
+
+// Check returns a line for every slash command name (ignoring case) used more than once, and every chat command
+// name discord would reject for not being lower case
+func Check() []string {
+	var collisions []string
+	seen := make(map[string]bool)
+	for _, cmd := range cmds {
+		key := strings.ToLower(cmd.Name)
+		if seen[key] {
+			collisions = append(collisions, fmt.Sprintf("%q is the name of more than one command", key))
+		}
+		seen[key] = true
+
+		if (cmd.Type == 0 || cmd.Type == discordgo.ChatApplicationCommand) && cmd.Name != key {
+			collisions = append(collisions, fmt.Sprintf("%q has to be lower case, discord only allows lower case names for chat commands", cmd.Name))
+		}
 	}
+	return collisions
+}
+
+// newCommand adds a command to the Commands map, keyed in lower case so Get doesnt have to scan
+func newCommand(c Command) {
 	lock.Lock()
 	defer lock.Unlock()
-	Commands[c.Name] = &c
+	Commands[strings.ToLower(c.Name)] = &c
 }
 
-// Load registers all commands with Discord
-func Load(s *discordgo.Session) {
+// Load syncs our commands with Discord in every place they could be registered, creating and updating the ones
+// scoped there and removing copies a command left behind when it moved scopes
+// it returns an error when any place couldnt be synced so we dont report commands as synced when they arent
+func Load(s *discordgo.Session) error {
 	// here we clear any existing data on startup to prevent duplicates
 	// this way if the bot crashes or gets restarted, we dont duplicate commands
 	RegisteredCommands = nil
 	RegisteredCommandIDs = nil
 
-	for _, v := range cmds {
-		newCommand(v)
-	}
+	targets := Targets()
+	var failed []string
+	for _, guildID := range targets {
+		changes, err := Sync(s, s.State.User.ID, guildID, false)
+		for _, c := range changes {
+			switch c.Action {
+			case ChangeDelete:
+				logging.Custom("⚙️ ", "COMMAND", "Removed stale slash command: %s (%s)", color.BgYellow, color.FgBlack, c.Name, scopeName(guildID))
+				continue
+			case ChangeCreate:
+				logging.Custom("⚙️ ", "COMMAND", "Registered slash command: %s (%s)", color.BgGreen, color.FgBlack, c.Name, scopeName(guildID))
+			case ChangeUpdate:
+				logging.Custom("⚙️ ", "COMMAND", "Updated slash command: %s (%s)", color.BgGreen, color.FgBlack, c.Name, scopeName(guildID))
+			}
 
-	for _, cmd := range cmds {
-		// here we create a discordgo.ApplicationCommand from our Command struct above
-		// this is what we actually register with Discord
-		// i did this so we can have our own struct with an Execute function
-		discordCmd := &discordgo.ApplicationCommand{
-			Name:        cmd.Name,
-			Type:        cmd.Type,
-			Description: cmd.Description,
-			Options:     cmd.Options,
+			// we store the registered commands and their IDs so we can deregister them later if configured,
+			// discord tells us which guild each one is in so Unload knows where to look
+			RegisteredCommands = append(RegisteredCommands, c.Remote)
+			RegisteredCommandIDs = append(RegisteredCommandIDs, c.Remote.ID)
 		}
-
-		// now we want to register our commands with Discord
-		// we store the registered commands and their IDs so we can deregister them later if configured
-		registeredCmd, err := s.ApplicationCommandCreate(s.State.User.ID, config.Config.GuildID, discordCmd)
 		if err != nil {
-			// we shouldnt reach here but just in case (i like my logs clean..)
-			logrite.Error("Cannot create '%v' command: %v", cmd.Name, err)
-			continue
+			logging.Error("Cannot sync slash commands in %s: %v", scopeName(guildID), err)
+			failed = append(failed, guildID)
 		}
+	}
+	logging.Info("%d slash command(s) registered", len(RegisteredCommands))
 
-		// here we are just appending the registered command to our slices
-		// so we can deregister them later if needed
-		RegisteredCommands = append(RegisteredCommands, registeredCmd)
-		RegisteredCommandIDs = append(RegisteredCommandIDs, registeredCmd.ID)
-		logrite.Custom("⚙️ ", "COMMAND", "Registered slash command: %s", color.BgGreen, color.FgBlack, registeredCmd.Name)
+	// places that failed stay on the list so the next start tries to clean them up again
+	remember := make(map[string]bool)
+	for _, guildID := range append(configTargets(), failed...) {
+		remember[guildID] = true
 	}
+	if err := settings.SetSlashTargets(sortedTargets(remember)); err != nil {
+		logging.Warn("Failed to remember where slash commands are registered: %v", err)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("couldnt sync slash commands in %d of %d place(s)", len(failed), len(targets))
+	}
+	return nil
 }
 
 // Unload deregisters all commands from Discord
 func Unload(s *discordgo.Session) {
 	if !config.Config.DeRegisterCommandsAfterRestart {
-		logrite.Info("Command deregistration is disabled")
+		logging.Info("Command deregistration is disabled")
 		return
 	}
 
 	for i, cmdID := range RegisteredCommandIDs {
-		err := s.ApplicationCommandDelete(s.State.User.ID, config.Config.GuildID, cmdID)
+		// each command is deleted from wherever its scope put it
+		guildID := ""
+		if i < len(RegisteredCommands) {
+			guildID = RegisteredCommands[i].GuildID
+		}
+		err := s.ApplicationCommandDelete(s.State.User.ID, guildID, cmdID)
 		if err != nil {
 			// only way this would fail is if the command ID is invalid or Discord is having issues
-			logrite.Error("Failed to delete command ID %s: %v", cmdID, err)
+			logging.Error("Failed to delete command ID %s: %v", cmdID, err)
 		} else {
 			if i < len(RegisteredCommands) {
-				logrite.Custom("⚙️ ", "COMMAND", "Deregistered: %s", color.BgGreen, color.FgWhite, RegisteredCommands[i].Name)
+				logging.Custom("⚙️ ", "COMMAND", "Deregistered: %s", color.BgGreen, color.FgWhite, RegisteredCommands[i].Name)
 			} else {
-				logrite.Custom("⚙️ ", "COMMAND", "Deregistered: %s", color.BgGreen, color.BgBlack, cmdID)
+				logging.Custom("⚙️ ", "COMMAND", "Deregistered: %s", color.BgGreen, color.BgBlack, cmdID)
 			}
 		}
 	}
@@ -131,21 +210,21 @@ func Unload(s *discordgo.Session) {
 
 // Get retrieves a command by name
 func Get(cmd string) (*Command, bool) {
-	for _, v := range Commands {
-		// case insensitive comparison so users can use any case (help, Help, HELP, HeLp) and so on
-		if strings.EqualFold(v.Name, cmd) {
-			return v, true
-		}
-	}
-	// we cant find the command so we return nil and false
-	return nil, false
+	lock.Lock()
+	defer lock.Unlock()
+
+	// case insensitive so users can use any case (help, Help, HELP, HeLp) and so on
+	c, ok := Commands[strings.ToLower(cmd)]
+	return c, ok
 }
 
 // HasPermission is what we use to check if the user has permission to use the command
 func HasPermission(interaction *discordgo.InteractionCreate) bool {
+	// Member is only set in guilds, DMs put the user in interaction.User instead so we let util figure it out
+	userID := util.InteractionUserID(interaction.Interaction)
 	for _, v := range config.Config.AuthenticatedIds {
-		// interaction.Member.User.ID is the discord id of the user who used the command if it wasnt obvious (i definitely didnt add this line so this page was an even 150 lines..)
-		if v == interaction.Member.User.ID {
+		// userID is the discord id of the user who used the command if it wasnt obvious (i definitely didnt add this line so this page was an even 150 lines..)
+		if v == userID {
 			// well return true since the user is authorized
 			return true
 		}
@@ -0,0 +1,186 @@
+package slashcommands
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// what Diff decided to do with a command
+const (
+	ChangeCreate    = "create"
+	ChangeUpdate    = "update"
+	ChangeDelete    = "delete"
+	ChangeUnchanged = "unchanged"
+)
+
+// Change is a single step needed to get the commands registered on Discord to match ours
+type Change struct {
+	Action string
+	Name   string
+	Local  *discordgo.ApplicationCommand // nil for deletes
+	Remote *discordgo.ApplicationCommand // nil for creates
+}
+
+// definition is what we send to Discord for a command
+func (c Command) definition() *discordgo.ApplicationCommand {
+	def := &discordgo.ApplicationCommand{
+		Name:                     c.Name,
+		Type:                     c.Type,
+		Description:              c.Description,
+		Options:                  c.Options,
+		DefaultMemberPermissions: c.Permissions,
+	}
+	if len(c.Contexts) > 0 {
+		def.Contexts = &c.Contexts
+	}
+	if len(c.NameLocalizations) > 0 {
+		def.NameLocalizations = &c.NameLocalizations
+	}
+	if len(c.DescriptionLocalizations) > 0 {
+		def.DescriptionLocalizations = &c.DescriptionLocalizations
+	}
+	return def
+}
+
+// Remote returns the commands currently registered on Discord, an empty guildID means the global ones
+func Remote(s *discordgo.Session, appID, guildID string) ([]*discordgo.ApplicationCommand, error) {
+	return s.ApplicationCommands(appID, guildID)
+}
+
+// Diff works out what has to be created, updated or deleted so remote ends up matching local
+// commands that already match are returned too (as unchanged) so callers can list everything in one go
+// commands are matched by type and name since a chat command and a context menu can share a name
+func Diff(local, remote []*discordgo.ApplicationCommand) []Change {
+	remoteByKey := make(map[commandKey]*discordgo.ApplicationCommand, len(remote))
+	for _, r := range remote {
+		remoteByKey[keyOf(r)] = r
+	}
+
+	var changes []Change
+	seen := make(map[commandKey]bool, len(local))
+	for _, l := range local {
+		seen[keyOf(l)] = true
+		r, ok := remoteByKey[keyOf(l)]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Action: ChangeCreate, Name: l.Name, Local: l})
+		case !sameCommand(l, r):
+			changes = append(changes, Change{Action: ChangeUpdate, Name: l.Name, Local: l, Remote: r})
+		default:
+			changes = append(changes, Change{Action: ChangeUnchanged, Name: l.Name, Local: l, Remote: r})
+		}
+	}
+
+	// anything left on discord that we dont have anymore is stale
+	var stale []Change
+	for _, r := range remote {
+		if !seen[keyOf(r)] {
+			stale = append(stale, Change{Action: ChangeDelete, Name: r.Name, Remote: r})
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i].Name < stale[j].Name })
+
+	return append(changes, stale...)
+}
+
+// commandKey is what identifies a command on discord, names are only unique per type
+type commandKey struct {
+	Type discordgo.ApplicationCommandType
+	Name string
+}
+
+// keyOf returns the key of a command, discord treats a missing type as a chat command
+func keyOf(c *discordgo.ApplicationCommand) commandKey {
+	t := c.Type
+	if t == 0 {
+		t = discordgo.ChatApplicationCommand
+	}
+	return commandKey{Type: t, Name: c.Name}
+}
+
+// sameCommand compares the parts of a command we control, discord adds IDs and versions we dont care about
+func sameCommand(local, remote *discordgo.ApplicationCommand) bool {
+	// discord fills in contexts when we leave them out so we only compare them when we set them
+	contexts := local.Contexts
+	if contexts == nil {
+		contexts = remote.Contexts
+	}
+
+	// everything nests pretty deep so comparing the JSON is the easiest way to catch every change
+	a, _ := json.Marshal(normalized(local, contexts))
+	b, _ := json.Marshal(normalized(remote, contexts))
+	return string(a) == string(b)
+}
+
+// normalized strips a command down to what sameCommand compares, with the ways discord says "nothing" made the same
+func normalized(c *discordgo.ApplicationCommand, contexts *[]discordgo.InteractionContextType) *discordgo.ApplicationCommand {
+	out := &discordgo.ApplicationCommand{
+		Name:                     c.Name,
+		Type:                     keyOf(c).Type, // discord treats a missing type as a chat command
+		Description:              c.Description,
+		Options:                  c.Options,
+		DefaultMemberPermissions: c.DefaultMemberPermissions,
+		Contexts:                 contexts,
+		NameLocalizations:        c.NameLocalizations,
+		DescriptionLocalizations: c.DescriptionLocalizations,
+	}
+	if out.NameLocalizations != nil && len(*out.NameLocalizations) == 0 {
+		out.NameLocalizations = nil
+	}
+	if out.DescriptionLocalizations != nil && len(*out.DescriptionLocalizations) == 0 {
+		out.DescriptionLocalizations = nil
+	}
+	if len(out.Options) == 0 {
+		out.Options = nil
+	}
+	return out
+}
+
+// Sync makes the commands registered in a guild (or globally) match the ones scoped there, only touching the ones that changed
+// with dryRun set nothing is sent and the changes are just returned, otherwise Remote is what discord has after the change
+// when a request fails the changes made before it are returned with the error
+func Sync(s *discordgo.Session, appID, guildID string, dryRun bool) ([]Change, error) {
+	remote, err := Remote(s, appID, guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := Diff(DefinitionsFor(guildID), remote)
+	if dryRun {
+		return changes, nil
+	}
+
+	for n, c := range changes {
+		switch c.Action {
+		case ChangeCreate:
+			changes[n].Remote, err = s.ApplicationCommandCreate(appID, guildID, c.Local)
+		case ChangeUpdate:
+			changes[n].Remote, err = s.ApplicationCommandEdit(appID, guildID, c.Remote.ID, c.Local)
+		case ChangeDelete:
+			err = s.ApplicationCommandDelete(appID, guildID, c.Remote.ID)
+		}
+		if err != nil {
+			return changes[:n], err
+		}
+	}
+	return changes, nil
+}
+
+// Purge deletes every command registered on Discord (global or for the guild) and returns how many there were
+func Purge(s *discordgo.Session, appID, guildID string) (int, error) {
+	remote, err := Remote(s, appID, guildID)
+	if err != nil {
+		return 0, err
+	}
+	if len(remote) == 0 {
+		return 0, nil
+	}
+
+	// overwriting with nothing removes them all in one request instead of one per command
+	if _, err = s.ApplicationCommandBulkOverwrite(appID, guildID, []*discordgo.ApplicationCommand{}); err != nil {
+		return 0, err
+	}
+	return len(remote), nil
+}
@@ -1,9 +1,16 @@
 package slashcommands
 
 import (
+	"context"
 	"fmt"
-	"template/config"
+	"strings"
+	"template/buildinfo"
+	"template/logging"
+	"template/monitor"
+	"template/shards"
 	"template/util"
+	"template/util/templates"
+	"template/util/timeutil"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -12,139 +19,137 @@ import (
 // StartTime stores when the bot was started
 var StartTime = time.Now()
 
-// Uptime calculates and shows how long the bot has been running since startup
-func Uptime(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	now := time.Now()
+// uptimeRefreshID is the custom ID of the refresh button under the status embed
+const uptimeRefreshID = "uptime:refresh"
 
-	// this gets us years, months, days, hours, minutes, and seconds
-	years := now.Year() - StartTime.Year()
-	months := int(now.Month()) - int(StartTime.Month())
-	days := now.Day() - StartTime.Day()
-	hours := now.Hour() - StartTime.Hour()
-	minutes := now.Minute() - StartTime.Minute()
-	seconds := now.Second() - StartTime.Second()
-
-	// now we handle negative values by borrowing from the next unit
-	// this is like doing math on paper where you borrow from the next column and if that dont help explain it go back to math class
-	if seconds < 0 {
-		seconds += 60
-		minutes--
-	}
-	if minutes < 0 {
-		minutes += 60
-		hours--
-	}
-	if hours < 0 {
-		hours += 24
-		days--
-	}
-	if days < 0 {
-		// now we get the last day of the previous month so we can borrow properly
-		prevMonth := now.AddDate(0, -1, 0)
-		days += daysIn(prevMonth.Year(), prevMonth.Month())
-		months--
-	}
-	if months < 0 {
-		months += 12
-		years--
-	}
+// Uptime shows a status dashboard, how long the bot has been running plus runtime and host stats
+func Uptime(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	util.NewInteractionResponder(s, i.Interaction).Reply(&util.Response{
+		Embeds:     []*discordgo.MessageEmbed{statusEmbed(s, i.GuildID)},
+		Components: refreshButton(),
+	})
+}
 
-	// now we build the human-readable uptime string
-	// we only want to get the units that are greater than 0 because we dont want to show "0 Days"
-	var unit []string
-	if years > 0 {
-		if years == 1 {
-			unit = append(unit, "1 Year")
-		} else {
-			unit = append(unit, fmt.Sprintf("%d Years", years))
-		}
+// HandleUptimeRefresh re-renders the status embed in place when someone clicks refresh
+func HandleUptimeRefresh(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionMessageComponent || i.MessageComponentData().CustomID != uptimeRefreshID {
+		return
 	}
-	if months > 0 {
-		if months == 1 {
-			unit = append(unit, "1 Month")
-		} else {
-			unit = append(unit, fmt.Sprintf("%d Months", months))
-		}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{statusEmbed(s, i.GuildID)},
+			Components: refreshButton(),
+		},
+	})
+	if err != nil {
+		logging.Warn("Failed to refresh status: %v", err)
 	}
-	if days > 0 {
-		if days == 1 {
-			unit = append(unit, "1 Day")
-		} else {
-			unit = append(unit, fmt.Sprintf("%d Days", days))
-		}
+}
+
+// refreshButton is the button row under the status embed
+func refreshButton() []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "Refresh",
+				Style:    discordgo.SecondaryButton,
+				CustomID: uptimeRefreshID,
+				Emoji:    &discordgo.ComponentEmoji{Name: "🔄"},
+			},
+		}},
 	}
-	if hours > 0 {
-		if hours == 1 {
-			unit = append(unit, "1 Hour")
-		} else {
-			unit = append(unit, fmt.Sprintf("%d Hours", hours))
+}
+
+// statusEmbed builds the status dashboard from config/templates/uptime.json
+func statusEmbed(s *discordgo.Session, guildID string) *discordgo.MessageEmbed {
+	now := time.Now()
+
+	// this reads like "3 years, 5 months, 2 days and 4 minutes", months come off the real calendar
+	details := timeutil.HumanizeBetween(StartTime, now, timeutil.Options{})
+
+	// the rest of the dashboard is runtime and host stats
+	sys := monitor.ReadSystem()
+
+	guilds, channels := 0, 0
+	for _, session := range shards.Sessions() {
+		session.State.RLock()
+		guilds += len(session.State.Guilds)
+		for _, g := range session.State.Guilds {
+			channels += len(g.Channels)
 		}
+		session.State.RUnlock()
 	}
-	if seconds > 0 {
-		if seconds == 1 {
-			unit = append(unit, "1 second")
-		} else {
-			unit = append(unit, fmt.Sprintf("%d seconds", seconds))
-		}
+
+	rss := "n/a"
+	if sys.RSS > 0 {
+		rss = formatBytes(sys.RSS)
 	}
 
-	// here we join the unit with commas and "and" for the last item
-	// this makes it read like "3 Years, 5 Months and 2 Days" and so on
-	var details string
-	if len(unit) == 0 {
-		details = "Just started" // nobody is using the bot this fast :P
-	} else if len(unit) == 1 {
-		details = unit[0]
-	} else {
-		details = fmt.Sprintf("%s and %s",
-			joinWithCommas(unit[:len(unit)-1]),
-			unit[len(unit)-1])
+	load := "n/a"
+	if sys.HasLoad {
+		load = fmt.Sprintf("%.2f %.2f %.2f", sys.Load[0], sys.Load[1], sys.Load[2])
 	}
 
-	// here we format the main description with the date and detailed breakdown
-	uptime := fmt.Sprintf("%s has been running since **%s** (%s)",
-		config.Config.Brand.Name,
-		StartTime.Format("January 2nd, 2006"),
-		details)
-
-	// now we build our embed
-	embed := util.NewEmbed().
-		SetTitle("Uptime").                                              // title
-		SetDescription(uptime).                                          // uptime we calculated above
-		SetColor(255, 255, 255).                                         // i like white better
-		AddField("Started", fmt.Sprintf("<t:%d:F>", StartTime.Unix())).  // discord timestamp - shows exact start time
-		AddField("Duration", fmt.Sprintf("<t:%d:R>", StartTime.Unix())). // discord relative timestamp - shows "x time ago"
-		SetFooter(config.Config.Brand.Name, config.Config.Brand.Icon).   // bot branding in the footer
-		Truncate()                                                       // auto-truncate to Discord limits
-
-	// now we can send the response back to Discord
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,                                     // tell Discord were sending a message
-		Data: &discordgo.InteractionResponseData{Embeds: []*discordgo.MessageEmbed{embed.MessageEmbed}}, // now we send our embed
+	gc := fmt.Sprintf("%d GC cycles", sys.NumGC)
+	if !sys.LastGC.IsZero() {
+		gc += fmt.Sprintf(", last <t:%d:R>", sys.LastGC.Unix())
+	}
 
-	})
-}
+	// now we build our embed from config/templates/uptime.json
+	data := templates.NewData(s, guildID, nil).
+		With("started", StartTime.Format("January 2, 2006")).                      // the date we started
+		With("details", details).                                                  // uptime we calculated above
+		With("unix", StartTime.Unix()).                                            // for the discord timestamps (exact start time and "x time ago")
+		With("updated", now.Unix()).                                               // so people can tell how fresh the numbers are
+		With("latency", fmt.Sprintf("%dms", s.HeartbeatLatency().Milliseconds())). // latency of the shard this came from
+		With("guilds", guilds).
+		With("channels", channels).
+		With("heap", formatBytes(sys.HeapAlloc)).
+		With("rss", rss).
+		With("goroutines", sys.Goroutines).
+		With("gc", gc).
+		With("version", buildinfo.Get().String()).
+		With("go", sys.GoVersion).
+		With("os", sys.OS+"/"+sys.Arch).
+		With("cpus", sys.CPUs).
+		With("load", load).
+		With("shards", shardSummary(s.ShardID)) // how every shard is doing
 
-// daysIn returns the number of days in a given month/year
-// this is needed because months have different numbers of days
-func daysIn(year int, month time.Month) int {
-	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	return templates.Render("uptime", data).MessageEmbed
 }
 
-// joinWithCommas joins a slice of strings with commas
-// so we can make nice lists like "1 Year, 2 Months, 3 Days"
-func joinWithCommas(unit []string) string {
-	if len(unit) == 0 {
-		return ""
+// formatBytes formats a byte count like 12.3 MiB
+func formatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
 	}
-	if len(unit) == 1 {
-		return unit[0]
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
 	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
 
-	// start with the first part and add commas + spaces for the rest
-	result := unit[0]
-	for i := 1; i < len(unit); i++ {
-		result += ", " + unit[i]
+// shardSummary lists every shard with its status, latency and guild count, the shard this command came from gets marked
+func shardSummary(current int) string {
+	lines := make([]string, 0, shards.Count())
+	for _, st := range shards.Statuses() {
+		status := "🟢"
+		if !st.Ready {
+			status = "🔴"
+		}
+		line := fmt.Sprintf("%s `#%d` %dms • %d guilds", status, st.ID, st.Latency.Milliseconds(), st.Guilds)
+		if st.ID == current {
+			line += " (this server)"
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return "No shards"
 	}
-	return result
+	return strings.Join(lines, "\n")
 }
@@ -0,0 +1,16 @@
+package slashcommands
+
+import (
+	"context"
+	"template/buildinfo"
+	"template/util"
+	"template/util/templates"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Version shows which build is running, the layout lives in config/templates/version.json and is shared with .version
+func Version(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := templates.NewData(s, i.GuildID, util.InteractionUser(i.Interaction)).With("build", buildinfo.Get())
+	util.NewInteractionResponder(s, i.Interaction).Embed(templates.Render("version", data).MessageEmbed, false)
+}
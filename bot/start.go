@@ -1,88 +1,198 @@
 package bot
 
 import (
-	"os"
-	"os/signal"
+	"context"
+	"fmt"
 	"strings"
-	"syscall"
+	"sync"
+	"template/audit"
 	"template/bot/commands"
 	"template/bot/slashcommands"
+	"template/buildinfo"
 	"template/config"
+	"template/lifecycle"
+	"template/logging"
+	"template/monitor"
+	"template/presence"
+	"template/settings"
+	"template/shards"
+	"template/util"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/yourpov/logrite"
 )
 
-// Start starts the Discord bot
-func Start() {
-	discord, err := discordgo.New("Bot " + config.Config.Token)
+// Start starts the Discord bot and blocks until it shuts down, it returns the exit code for the process
+func Start() int {
+	// we only ask for the intents the enabled features need
+	gatewayIntents := intents()
+	warnPrivileged(gatewayIntents)
+
+	// big bots need more than one gateway connection, shard_count 0 lets Discord tell us how many
+	err := shards.Setup(config.Config.Token, config.Config.ShardCount, gatewayIntents)
 	if err != nil {
-		logrite.Error("Failed to open a conn to Discord: %v", err)
-		os.Exit(1)
+		logging.Error("Failed to set up shards: %v", err)
+		return 1
 	}
 
-	// we need these intents to tell if a user is using a prefix command
-	discord.Identify.Intents = discordgo.IntentsGuildMessages | discordgo.IntentsMessageContent
-
+	// every shard feeds the same handlers, discordgo hands them the session of the shard the event came from
 	if config.Config.PrefixEnabled {
-		discord.AddHandler(messageCreate)
+		// prefix commands dont need a session so we load them once here instead of on every READY
+		if err = commands.Load(); err != nil {
+			logging.Error("Failed to load prefix commands: %v", err)
+			return 1
+		}
+		shards.AddHandler(messageCreate)
 	}
 
-	discord.AddHandler(ready)
+	shards.AddHandler(ready)
+
+	// paginated menus (like .help) use buttons so we always listen for component interactions
+	shards.AddHandler(util.HandlePaginator)
 
 	if config.Config.SlashEnabled {
-		discord.AddHandler(handler)
+		// slash commands are only registered on READY but a collision should stop us before we connect
+		if err = slashcommands.Prepare(); err != nil {
+			logging.Error("Failed to load slash commands: %v", err)
+			return 1
+		}
+		shards.AddHandler(handler)
+		shards.AddHandler(slashcommands.HandleUptimeRefresh)
 	}
 
-	err = discord.Open()
+	// the audit log is opened before we connect so the very first command gets recorded
+	if err = audit.Open(); err != nil {
+		logging.Error("Failed to open audit log: %v", err)
+	}
+
+	// runtime settings (disabled commands and so on) have to be loaded before the first command comes in
+	if err = settings.Open(); err != nil {
+		logging.Error("Failed to load settings: %v", err)
+		return 1
+	}
+
+	// metrics and health probes are optional, the listener only starts when http_address is set
+	monitor.TrackShards()
+	monitor.SetSlashDisabled(!config.Config.SlashEnabled)
+	monitor.Start(config.Config.HTTPAddress)
+
+	registerShutdownHooks()
+
+	err = shards.Open()
 	if err != nil {
-		logrite.Error("Failed to open conn: %v", err)
-		os.Exit(1)
+		logging.Error("Failed to open conn: %v", err)
+		if strings.Contains(err.Error(), "4014") {
+			// 4014 is "disallowed intents", the message from discord doesnt say which one
+			logging.Error("Discord rejected our intents, make sure every privileged intent listed above is enabled in the Developer Portal")
+		}
+		lifecycle.Shutdown(shutdownTimeout())
+		return 1
 	}
-	defer discord.Close()
 
-	sc := make(chan os.Signal, 1)
-	// we want to listen for termination signals to gracefully shutdown
-	// this is especially important if you want to deregister commands on shutdown
-	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
-	<-sc
+	// the presence is set per shard in ready, this just keeps the activities rotating
+	presence.Start()
+
+	sig := lifecycle.WaitForSignal()
+	logging.Warn("Received %s", sig)
+
+	return lifecycle.Shutdown(shutdownTimeout())
+}
 
+// registerShutdownHooks tells the lifecycle manager how to stop each subsystem, they run in order once
+// every running command finished (or the drain timeout ran out)
+func registerShutdownHooks() {
 	// we want to unload slash commands on shutdown
 	// this is optional but recommended so users dont try to use commands when the bot is offline
-	// here we unload slash commands
 	if config.Config.SlashEnabled && config.Config.DeRegisterCommandsAfterRestart {
-		logrite.Warn("Shutting down, deregistering commands...")
-		slashcommands.Unload(discord)
+		lifecycle.OnShutdown("deregister commands", lifecycle.OrderDiscord, func(ctx context.Context) error {
+			logging.Warn("Deregistering commands...")
+			slashcommands.Unload(shards.Primary())
+			return nil
+		})
 	}
 
+	// open menus get their buttons disabled while we can still edit messages
+	lifecycle.OnShutdown("close paginators", lifecycle.OrderDiscord, func(ctx context.Context) error {
+		util.ClosePaginators()
+		return nil
+	})
+
+	// any temporary messages still waiting get deleted now instead of hanging around forever
+	lifecycle.OnShutdown("flush temporary messages", lifecycle.OrderDiscord, func(ctx context.Context) error {
+		util.FlushTemporary()
+		return nil
+	})
+
+	// the rotation talks to the shards so it has to stop before they close
+	lifecycle.OnShutdown("stop presence rotation", lifecycle.OrderDiscord, func(ctx context.Context) error {
+		presence.Stop()
+		return nil
+	})
+
+	lifecycle.OnShutdown("close shards", lifecycle.OrderSession, func(ctx context.Context) error {
+		return shards.Close()
+	})
+
+	lifecycle.OnShutdown("stop monitor", lifecycle.OrderServers, monitor.Stop)
+
+	lifecycle.OnShutdown("close audit log", lifecycle.OrderStorage, func(ctx context.Context) error {
+		return audit.Close()
+	})
+
+	lifecycle.OnShutdown("close log file", lifecycle.OrderLogging, func(ctx context.Context) error {
+		logging.Close()
+		return nil
+	})
 }
 
-// ready is a handler for when the bot is ready
+// shutdownTimeout returns the drain timeout from the config
+func shutdownTimeout() time.Duration {
+	return time.Duration(config.Config.ShutdownTimeout) * time.Second
+}
+
+// slashLoaded makes sure slash commands are only registered once, READY comes in again every time a shard
+// has to start a fresh session and registering twice would trip the duplicate command check
+var slashLoaded sync.Once
+
+// ready is a handler for when a shard is ready
 func ready(session *discordgo.Session, event *discordgo.Ready) {
-	logrite.Info("Brand: %s", config.Config.Brand.Name)
-	logrite.Info("User: %s (%s)", session.State.User.Username, session.State.User.ID)
+	// discord forgets our presence whenever a shard starts a fresh session so we set it again
+	presence.Restore(session)
+
+	if session.ShardCount > 1 {
+		logging.Info("Shard %d/%d ready (%d guilds)", session.ShardID+1, session.ShardCount, len(event.Guilds))
+	}
+
+	// everything below only needs to happen once, not once per shard
+	if session.ShardID != 0 {
+		return
+	}
+
+	logging.Info("Brand: %s", config.Config.Brand.Name)
+	logging.Info("Version: %s", buildinfo.Get())
+	logging.Info("User: %s (%s)", session.State.User.Username, session.State.User.ID)
 
 	if config.Config.SlashEnabled && config.Config.PrefixEnabled {
-		logrite.Info("Mode: Prefix/Slash")
+		logging.Info("Mode: Prefix/Slash")
 	} else if config.Config.SlashEnabled {
-		logrite.Info("Mode: Slash")
+		logging.Info("Mode: Slash")
 	} else if config.Config.PrefixEnabled {
-		logrite.Info("Mode: Prefix")
+		logging.Info("Mode: Prefix")
 	} else {
-		logrite.Warn("Enable at least one command mode in config/config.json")
-	}
-
-	if config.Config.PrefixEnabled {
-		// load our prefix commands if enabled
-		commands.Load()
-		//logrite.Success("Prefix Commands Loaded")
+		logging.Warn("Enable at least one command mode in config/config.json")
 	}
 
 	if config.Config.SlashEnabled {
-		// load our slash commands if enabled
-		slashcommands.Load(session)
-		//logrite.Success("Slash Commands Loaded")
+		// load our slash commands if enabled, commands are global to the bot so only shard 0 registers them
+		slashLoaded.Do(func() {
+			// /readyz should only say the commands are synced when discord actually has all of them
+			if err := slashcommands.Load(session); err != nil {
+				logging.Error("Slash commands arent fully registered: %v", err)
+				return
+			}
+			monitor.SetCommandsSynced(true)
+			//logging.Success("Slash Commands Loaded")
+		})
 	}
 }
 
@@ -93,34 +203,122 @@ func messageCreate(session *discordgo.Session, m *discordgo.MessageCreate) {
 		return
 	}
 
-	args := strings.Split(m.Content, " ")
+	guild := settings.Resolve(m.GuildID)
+	botID := session.State.User.ID
+
+	// someone pinging the bot with nothing else probably wants to know how to talk to it
+	if isBareMention(m.Content, botID) {
+		util.NewMessageResponder(session, m.Message).Text(prefixHint(guild), false)
+		return
+	}
+
+	// prefixes can be followed by a space (". ping") so we split what comes after the prefix instead of the raw message
+	rest, matched := matchPrefix(m.Content, guild, botID)
+	if matched {
+		args := strings.Fields(rest)
+		if len(args) == 0 {
+			// shouldnt happen since matchPrefix trims whitespace, but an index panic here would take the whole bot down
+			return
+		}
+
+		// once we are shutting down we stop picking up new commands, running ones get to finish
+		done, accepting := lifecycle.Track()
+		if !accepting {
+			return
+		}
+		defer done()
+
+		ok, command := commands.GetCommand(args[0], m)
+		inv := invocation{session: session, id: m.ID, kind: "prefix", userID: m.Author.ID, username: m.Author.Username, guildID: m.GuildID, channelID: m.ChannelID, args: args[1:]}
+		if command != nil {
+			inv.name = command.Name
+			inv.admin = command.AdminOnly
 
-	if strings.HasPrefix(args[0], config.Config.Prefix) {
-		stripped := strings.TrimPrefix(args[0], config.Config.Prefix)
-		ok, command := commands.GetCommand(stripped, m)
+			// admins can turn commands off per channel, server or everywhere with .command
+			where := access(session, m.GuildID, m.ChannelID, m.Member)
+			if disabled, level := settings.DisabledAt(command.Name, m.GuildID, where.ChannelIDs); disabled {
+				util.SendTemporary(session, m.ChannelID, settings.DisabledMessage(level), util.TempOptions{Invoker: m.Message})
+				inv.record(monitor.OutcomeDisabled, 0)
+				return
+			}
+
+			// then the channel, category and role allow/deny lists
+			if allowed, reason := settings.CheckAccess(command.Name, where); !allowed {
+				// the reason can mention roles so we make sure nobody gets pinged by it
+				util.SendTemporaryComplex(session, m.ChannelID, &discordgo.MessageSend{
+					Content:         reason,
+					AllowedMentions: &discordgo.MessageAllowedMentions{},
+				}, util.TempOptions{Invoker: m.Message})
+				inv.record(monitor.OutcomeForbidden, 0)
+				return
+			}
+		}
 
 		if !ok && command != nil {
-			// here we send a temp message and delete it after 5s to mimic ephemeral since discordgo dont support ephem messages in normal text channels like clyde :(
-			msg, _ := session.ChannelMessageSend(m.ChannelID, "You are not authorized to use this command")
-
-			go func() {
-				if msg != nil {
-					<-time.After(5 * time.Second)
-					session.ChannelMessageDelete(m.ChannelID, msg.ID)
-				}
-			}()
+			// here we send a temp message that gets deleted after 5s to mimic ephemeral since discordgo dont support ephem messages in normal text channels like clyde :(
+			util.SendTemporary(session, m.ChannelID, "You are not authorized to use this command", util.TempOptions{Invoker: m.Message})
+			inv.record(monitor.OutcomeUnauthorized, 0)
 			return
 		} else if ok {
-			command.Execute(session, m, args)
+			inv.run(func(ctx context.Context) { command.Execute(ctx, session, m, args) })
 		} else if !ok && command == nil {
+			// we dont use what the user typed as the name or anyone could spam new series into our metrics
+			inv.name = "unknown"
+			inv.record(monitor.OutcomeNotFound, 0)
+
+			// other bots with the same prefix would have us complaining about every one of their commands
+			if config.Config.IgnoreUnknownCommands {
+				return
+			}
 			// we do the same thing here ^^
-			notFound, _ := session.ChannelMessageSend(m.ChannelID, "Command not found")
-			<-time.After(5 * time.Second)
-			session.ChannelMessageDelete(m.ChannelID, notFound.ID)
+			util.SendTemporary(session, m.ChannelID, notFoundMessage(session, m, guild, args[0]), util.TempOptions{Invoker: m.Message})
 		}
 	}
 }
 
+// notFoundMessage tells the user we dont have a command and suggests the closest ones they could actually run here
+func notFoundMessage(s *discordgo.Session, m *discordgo.MessageCreate, guild settings.Guild, typed string) string {
+	where := access(s, m.GuildID, m.ChannelID, m.Member)
+	suggestions := commands.Suggest(typed, 3, func(cmd *commands.Command) bool {
+		if !commands.Authorized(cmd, m.Author.ID) {
+			return false
+		}
+		if disabled, _ := settings.DisabledAt(cmd.Name, m.GuildID, where.ChannelIDs); disabled {
+			return false
+		}
+		allowed, _ := settings.CheckAccess(cmd.Name, where)
+		return allowed
+	})
+	if len(suggestions) == 0 {
+		return "Command not found"
+	}
+
+	for i, name := range suggestions {
+		suggestions[i] = "`" + guild.Prefix + name + "`"
+	}
+	last := len(suggestions) - 1
+	if last == 0 {
+		return "Command not found, did you mean " + suggestions[0] + "?"
+	}
+	return "Command not found, did you mean " + strings.Join(suggestions[:last], ", ") + " or " + suggestions[last] + "?"
+}
+
+// prefixHint is the reply to a bare mention, it lists every prefix that works in the guild
+func prefixHint(guild settings.Guild) string {
+	hint := fmt.Sprintf("My prefix here is `%s`, try `%shelp`", guild.Prefix, guild.Prefix)
+	if len(guild.Prefixes) > 1 {
+		others := make([]string, 0, len(guild.Prefixes)-1)
+		for _, prefix := range guild.Prefixes[1:] {
+			others = append(others, "`"+prefix+"`")
+		}
+		hint += fmt.Sprintf("\nThese work too: %s", strings.Join(others, ", "))
+	}
+	if config.Config.MentionPrefix {
+		hint += "\nYou can also mention me instead of using a prefix"
+	}
+	return hint
+}
+
 // handler is a handler for slash commands
 func handler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	if i.Type != discordgo.InteractionApplicationCommand {
@@ -133,19 +331,45 @@ func handler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	data := i.ApplicationCommandData()
 	command, ok := slashcommands.Get(data.Name)
 
+	done, accepting := lifecycle.Track()
+	if !accepting {
+		// slash commands show "application did not respond" if we just ignore them so we say why
+		util.NewInteractionResponder(s, i.Interaction).Text("The bot is restarting, try again in a moment", true)
+		return
+	}
+	defer done()
+
 	if ok && command != nil {
+		user := util.InteractionUser(i.Interaction)
+		inv := invocation{session: s, id: i.ID, name: command.Name, kind: "slash", userID: user.ID, username: user.Username, guildID: i.GuildID, channelID: i.ChannelID, args: optionArgs(data.Options), admin: command.Admin}
+		// discord only gives us a few seconds from when the interaction was created, not from when we got it
+		if created, err := discordgo.SnowflakeTimestamp(i.ID); err == nil {
+			inv.respondBy = created.Add(util.ResponseWindow)
+		}
+
+		// we cant unregister a command for a single channel so disabled ones stay visible and get a clear no instead
+		where := access(s, i.GuildID, i.ChannelID, i.Member)
+		if disabled, level := settings.DisabledAt(command.Name, i.GuildID, where.ChannelIDs); disabled {
+			util.NewInteractionResponder(s, i.Interaction).Text(settings.DisabledMessage(level), true)
+			inv.record(monitor.OutcomeDisabled, 0)
+			return
+		}
+
+		if allowed, reason := settings.CheckAccess(command.Name, where); !allowed {
+			util.NewInteractionResponder(s, i.Interaction).Text(reason, true)
+			inv.record(monitor.OutcomeForbidden, 0)
+			return
+		}
+
 		if command.Admin {
 			if slashcommands.HasPermission(i) {
-				command.Execute(s, i)
+				inv.run(func(ctx context.Context) { command.Execute(ctx, s, i) })
 			} else {
-				s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-					Type: discordgo.InteractionResponseChannelMessageWithSource,
-					Data: &discordgo.InteractionResponseData{
-						Content: "You are not permitted to use this command",
-						Flags:   discordgo.MessageFlagsEphemeral}})
+				util.NewInteractionResponder(s, i.Interaction).Text("You are not permitted to use this command", true)
+				inv.record(monitor.OutcomeUnauthorized, 0)
 			}
 		} else {
-			command.Execute(s, i)
+			inv.run(func(ctx context.Context) { command.Execute(ctx, s, i) })
 		}
 	}
 }
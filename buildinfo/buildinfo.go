@@ -0,0 +1,135 @@
+package buildinfo
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// these are set at build time with -ldflags (manage.sh does this for you), e.g.
+//
+//	go build -ldflags "-X template/buildinfo.Version=v1.2.0 -X template/buildinfo.Commit=$(git rev-parse HEAD)"
+//
+// anything left empty falls back to what the go toolchain stamped into the binary
+var (
+	Version = ""
+	Commit  = ""
+	Date    = "" // RFC3339
+	Dirty   = "" // "true" when the tree had uncommitted changes
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string
+	Commit    string
+	Date      time.Time // zero when unknown
+	Dirty     bool
+	GoVersion string
+	Module    string
+	Deps      []Dep
+}
+
+// Dep is a module dependency compiled into the binary
+type Dep struct {
+	Path    string
+	Version string
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get returns the build info, ldflags win over what debug.ReadBuildInfo knows
+func Get() Info {
+	once.Do(func() { info = read() })
+	return info
+}
+
+// read puts the build info together
+func read() Info {
+	i := Info{GoVersion: runtime.Version(), Version: "dev"}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		i.Module = bi.Main.Path
+		if bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			i.Version = bi.Main.Version
+		}
+
+		// go build stamps the vcs details when it's run inside a git checkout
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				i.Commit = s.Value
+			case "vcs.time":
+				i.Date, _ = time.Parse(time.RFC3339, s.Value)
+			case "vcs.modified":
+				i.Dirty = s.Value == "true"
+			}
+		}
+
+		for _, d := range bi.Deps {
+			// replaced modules show what we actually built with
+			if d.Replace != nil {
+				d = d.Replace
+			}
+			i.Deps = append(i.Deps, Dep{Path: d.Path, Version: d.Version})
+		}
+	}
+
+	if Version != "" {
+		i.Version = Version
+	}
+	if Commit != "" {
+		i.Commit = Commit
+	}
+	if Date != "" {
+		if t, err := time.Parse(time.RFC3339, Date); err == nil {
+			i.Date = t
+		}
+	}
+	if Dirty != "" {
+		i.Dirty = Dirty == "true"
+	}
+	return i
+}
+
+// ShortCommit returns the first 7 characters of the commit, or "unknown"
+func (i Info) ShortCommit() string {
+	if i.Commit == "" {
+		return "unknown"
+	}
+	if len(i.Commit) > 7 {
+		return i.Commit[:7]
+	}
+	return i.Commit
+}
+
+// String returns a one line summary like "v1.2.0 (abc1234, dirty)"
+func (i Info) String() string {
+	s := fmt.Sprintf("%s (%s", i.Version, i.ShortCommit())
+	if i.Dirty {
+		s += ", dirty"
+	}
+	return s + ")"
+}
+
+// DepsList returns up to max dependencies as "path version" lines, the rest are summed up at the end
+func (i Info) DepsList(max int) string {
+	if len(i.Deps) == 0 {
+		return "none"
+	}
+
+	lines := make([]string, 0, max+1)
+	for n, d := range i.Deps {
+		if n == max {
+			lines = append(lines, fmt.Sprintf("…and %d more", len(i.Deps)-max))
+			break
+		}
+		lines = append(lines, d.Path+" "+d.Version)
+	}
+	return strings.Join(lines, "\n")
+}
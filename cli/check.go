@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"fmt"
+	"template/bot"
+	"template/bot/commands"
+	"template/bot/slashcommands"
+	"template/config"
+	"template/presence"
+	"template/util"
+	"template/util/templates"
+)
+
+// checkConfig validates the config and templates, it exits 1 when anything is wrong so it can gate a deploy
+func checkConfig(args []string) int {
+	fs := newFlagSet("check-config")
+	path := fs.String("config", config.Path, "config file to check")
+	online := fs.Bool("online", false, "also log in with the token to make sure Discord accepts it")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	c, err := config.Read(*path)
+	if err != nil {
+		return failf("%v", err)
+	}
+	// the checks below read the global config like the bot does
+	config.Config = c
+
+	problems := c.Validate()
+	problems = append(problems, presence.Check(c.Presence)...)
+	problems = append(problems, util.CheckTheme(c.Theme)...)
+	problems = append(problems, bot.UnknownIntents()...)
+	for _, c := range commands.Check() {
+		problems = append(problems, "prefix command collision: "+c)
+	}
+	for _, c := range slashcommands.Check() {
+		problems = append(problems, "slash command collision: "+c)
+	}
+
+	count, err := templates.Check()
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("embed template %v", err))
+	}
+
+	if *online && c.Token != "" {
+		if _, _, err := restSession(); err != nil {
+			problems = append(problems, fmt.Sprintf("token was rejected: %v", err))
+		}
+	}
+
+	if len(problems) > 0 {
+		fmt.Printf("%s has %d problem(s):\n", *path, len(problems))
+		for _, p := range problems {
+			fmt.Printf("  ✗ %s\n", p)
+		}
+		return 1
+	}
+
+	fmt.Printf("✓ %s is valid (%d embed templates)\n", *path, count)
+	return 0
+}
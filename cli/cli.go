@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"template/config"
+	"text/tabwriter"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// subcommand is a single `./template <name>` operation
+type subcommand struct {
+	name        string
+	usage       string
+	description string
+	run         func(args []string) int
+}
+
+// subcommands is filled in init since the help subcommand needs to read it
+var subcommands []subcommand
+
+func init() {
+	subcommands = []subcommand{
+		{"run", "run", "Start the bot (the default when no subcommand is given)", runBot},
+		{"check-config", "check-config [-config path] [-online]", "Validate the config and embed templates without starting the bot", checkConfig},
+		{"commands", "commands <list|sync|purge|export|register> [flags]", "Manage the slash commands registered on Discord, or export/register them as a manifest", commandsCmd},
+		{"install-service", "install-service [-name discord-bot] [-user name] [-output path]", "Generate a systemd unit file for the bot", installService},
+		{"status", "status [-address host:port]", "Ask a running bot for its health through the monitor listener", status},
+		{"help", "help", "Show this help", func([]string) int {
+			usage(os.Stdout)
+			return 0
+		}},
+	}
+}
+
+// Run runs the subcommand named in args (os.Args without the program name) and returns the exit code
+// no args runs the bot so existing service files and scripts that just start the binary keep working
+func Run(args []string) int {
+	if len(args) == 0 {
+		return runBot(nil)
+	}
+
+	name := args[0]
+	switch name {
+	case "-h", "-help", "--help":
+		name = "help"
+	}
+
+	for _, sub := range subcommands {
+		if sub.name == name {
+			return sub.run(args[1:])
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	usage(os.Stderr)
+	return 2
+}
+
+// usage prints every subcommand and what it does
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s <command> [flags]\n\nCommands:\n", os.Args[0])
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, sub := range subcommands {
+		fmt.Fprintf(tw, "  %s\t%s\n", sub.usage, sub.description)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\nRun '%s <command> -h' to see the flags of a command\n", os.Args[0])
+}
+
+// newFlagSet returns a flag set that prints errors instead of exiting so we control the exit code
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+// failf prints an error to stderr and returns the exit code for a failed command
+func failf(format string, args ...any) int {
+	fmt.Fprintf(os.Stderr, "error: "+format+"\n", args...)
+	return 1
+}
+
+// restSession returns a session for talking to the REST API without opening a gateway connection,
+// along with the application ID the commands are registered under
+func restSession() (*discordgo.Session, string, error) {
+	s, err := discordgo.New("Bot " + config.Config.Token)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// commands are registered under the application, which isnt always the same ID as the bot user on older apps
+	app, err := s.Application("@me")
+	if err != nil {
+		return nil, "", fmt.Errorf("logging in: %w", err)
+	}
+	return s, app.ID, nil
+}
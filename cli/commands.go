@@ -0,0 +1,322 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"template/bot/commands"
+	"template/bot/slashcommands"
+	"template/config"
+	"template/settings"
+	"text/tabwriter"
+)
+
+// commandsCmd handles `commands list|sync|purge|export|register`
+func commandsCmd(args []string) int {
+	if len(args) == 0 {
+		commandsUsage(os.Stderr)
+		return 2
+	}
+	// asking for help isnt a mistake so it goes to stdout and exits 0, like the top level help does
+	switch args[0] {
+	case "-h", "-help", "--help", "help":
+		commandsUsage(os.Stdout)
+		return 0
+	}
+
+	fs := newFlagSet("commands " + args[0])
+	guild := fs.String("guild", "", "only work on the commands in this guild")
+	global := fs.Bool("global", false, "only work on the global commands")
+
+	// list, sync and purge go over every guild our scopes use unless told otherwise,
+	// a manifest is a single bulk overwrite so export and register work on one place (guild_id by default, like Load did)
+	var run func(targets []string) int
+	single := false
+	switch args[0] {
+	case "list":
+		remote := fs.Bool("remote", false, "compare against what is registered on Discord")
+		run = func(targets []string) int { return listCommands(targets, *remote) }
+	case "sync":
+		dryRun := fs.Bool("dry-run", false, "only show what would change")
+		run = func(targets []string) int { return syncCommands(targets, *dryRun) }
+	case "purge":
+		yes := fs.Bool("yes", false, "actually delete them, without it we only say how many there are")
+		run = func(targets []string) int { return purgeCommands(targets, *yes) }
+	case "export":
+		output := fs.String("output", "", "file to write the manifest to (defaults to stdout)")
+		check := fs.Bool("check", false, "dont write anything, exit 1 if -output is out of date (handy in CI)")
+		run = func(targets []string) int { return exportManifest(targets[0], *output, *check) }
+		single = true
+	case "register":
+		manifest := fs.String("manifest", "", "manifest file to register (from commands export)")
+		dryRun := fs.Bool("dry-run", false, "only show what would change")
+		run = func(targets []string) int { return registerManifest(*manifest, targets[0], *dryRun) }
+		single = true
+	default:
+		fmt.Fprintf(os.Stderr, "unknown commands subcommand %q, use list, sync, purge, export or register\n", args[0])
+		return 2
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	config.Load()
+	// the settings remember where the bot registered commands last, so a guild dropped from the config is synced too
+	if err := settings.Open(); err != nil {
+		return failf("reading settings: %v", err)
+	}
+
+	var targets []string
+	switch {
+	case *global:
+		targets = []string{""}
+	case *guild != "":
+		targets = []string{*guild}
+	case single:
+		targets = []string{config.Config.GuildID}
+	default:
+		targets = slashcommands.Targets()
+	}
+	return run(targets)
+}
+
+// commandsUsage prints the commands subcommands and what they do
+func commandsUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s commands <subcommand> [flags]\n\nSubcommands:\n", os.Args[0])
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  list [-remote]\tList prefix and slash commands, -remote compares them with Discord")
+	fmt.Fprintln(tw, "  sync [-dry-run]\tCreate, update and remove slash commands so Discord matches ours")
+	fmt.Fprintln(tw, "  purge [-yes]\tDelete every slash command registered on Discord")
+	fmt.Fprintln(tw, "  export [-output path] [-check]\tWrite our slash commands to a manifest")
+	fmt.Fprintln(tw, "  register -manifest path [-dry-run]\tMake Discord match a manifest")
+	tw.Flush()
+	fmt.Fprintf(w, "\nlist, sync and purge take -guild id or -global, export and register default to guild_id\n")
+	fmt.Fprintf(w, "Run '%s commands <subcommand> -h' to see every flag\n", os.Args[0])
+}
+
+// listCommands prints our prefix and slash commands, and with remote how each slash command compares to Discord
+func listCommands(targets []string, remote bool) int {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "Prefix commands (prefix %q):\n", config.Config.Prefix)
+	for _, cmd := range commands.List() {
+		fmt.Fprintf(w, "  %s%s\t%s\t%s\n", config.Config.Prefix, cmd.Name, adminLabel(cmd.AdminOnly), aliasLabel(cmd.Alias))
+	}
+
+	if !remote {
+		fmt.Fprintln(w, "\nSlash commands:")
+		for _, cmd := range slashcommands.List() {
+			fmt.Fprintf(w, "  /%s\t%s\t%s\t%s\n", cmd.Name, adminLabel(cmd.Admin), scopeLabel(slashcommands.ScopeTargets(cmd)), cmd.Description)
+		}
+		return 0
+	}
+
+	s, appID, err := restSession()
+	if err != nil {
+		return failf("%v", err)
+	}
+
+	for _, guildID := range targets {
+		registered, err := slashcommands.Remote(s, appID, guildID)
+		if err != nil {
+			return failf("fetching %s commands: %v", slashcommands.ScopeName(guildID), err)
+		}
+
+		fmt.Fprintf(w, "\nSlash commands (%s):\n", slashcommands.ScopeName(guildID))
+		changes := slashcommands.Diff(slashcommands.DefinitionsFor(guildID), registered)
+		if len(changes) == 0 {
+			fmt.Fprintln(w, "  none")
+		}
+		for _, c := range changes {
+			state := map[string]string{
+				slashcommands.ChangeCreate:    "not registered",
+				slashcommands.ChangeUpdate:    "out of date",
+				slashcommands.ChangeDelete:    "stale (registered but not scoped here anymore)",
+				slashcommands.ChangeUnchanged: "up to date",
+			}[c.Action]
+			id := "-"
+			if c.Remote != nil {
+				id = c.Remote.ID
+			}
+			fmt.Fprintf(w, "  /%s\t%s\t%s\n", c.Name, id, state)
+		}
+	}
+	return 0
+}
+
+// syncCommands makes Discord match our slash commands in every target and prints what it did
+func syncCommands(targets []string, dryRun bool) int {
+	s, appID, err := restSession()
+	if err != nil {
+		return failf("%v", err)
+	}
+
+	for _, guildID := range targets {
+		fmt.Printf("Slash commands (%s):\n", slashcommands.ScopeName(guildID))
+		changes, err := slashcommands.Sync(s, appID, guildID, dryRun)
+		if err != nil {
+			printChanges(changes, dryRun)
+			return failf("syncing %s commands: %v", slashcommands.ScopeName(guildID), err)
+		}
+		if !printChanges(changes, dryRun) {
+			fmt.Println("  already up to date")
+		}
+	}
+
+	if dryRun {
+		fmt.Println("Dry run, nothing was changed")
+	}
+	return 0
+}
+
+// printChanges prints every change that isnt a no-op and reports whether there were any
+func printChanges(changes []slashcommands.Change, dryRun bool) bool {
+	verbs := map[string]string{
+		slashcommands.ChangeCreate: "created",
+		slashcommands.ChangeUpdate: "updated",
+		slashcommands.ChangeDelete: "deleted",
+	}
+	if dryRun {
+		verbs = map[string]string{
+			slashcommands.ChangeCreate: "would create",
+			slashcommands.ChangeUpdate: "would update",
+			slashcommands.ChangeDelete: "would delete",
+		}
+	}
+
+	changed := false
+	for _, c := range changes {
+		if c.Action == slashcommands.ChangeUnchanged {
+			continue
+		}
+		changed = true
+		fmt.Printf("  %s /%s\n", verbs[c.Action], c.Name)
+	}
+	return changed
+}
+
+// purgeCommands deletes every slash command registered in the targets, handy when the bot leaves stale ones behind
+func purgeCommands(targets []string, yes bool) int {
+	s, appID, err := restSession()
+	if err != nil {
+		return failf("%v", err)
+	}
+
+	for _, guildID := range targets {
+		if !yes {
+			registered, err := slashcommands.Remote(s, appID, guildID)
+			if err != nil {
+				return failf("fetching %s commands: %v", slashcommands.ScopeName(guildID), err)
+			}
+			fmt.Printf("%d slash command(s) registered (%s)\n", len(registered), slashcommands.ScopeName(guildID))
+			continue
+		}
+
+		n, err := slashcommands.Purge(s, appID, guildID)
+		if err != nil {
+			return failf("purging %s commands: %v", slashcommands.ScopeName(guildID), err)
+		}
+		fmt.Printf("Deleted %d slash command(s) (%s)\n", n, slashcommands.ScopeName(guildID))
+	}
+
+	if !yes {
+		fmt.Println("Run again with -yes to delete them")
+	}
+	return 0
+}
+
+// exportManifest writes the manifest of our slash commands, with check it compares it to the file instead
+func exportManifest(guildID, output string, check bool) int {
+	manifest, err := slashcommands.Manifest(guildID)
+	if err != nil {
+		return failf("building the manifest: %v", err)
+	}
+
+	if check {
+		if output == "" {
+			return failf("-check needs -output to know which file to compare against")
+		}
+		existing, err := os.ReadFile(output)
+		if err != nil {
+			return failf("%v", err)
+		}
+		if !bytes.Equal(existing, manifest) {
+			fmt.Printf("✗ %s is out of date, run commands export -output %s\n", output, output)
+			return 1
+		}
+		fmt.Printf("✓ %s is up to date\n", output)
+		return 0
+	}
+
+	if output == "" {
+		os.Stdout.Write(manifest)
+		return 0
+	}
+	if err = os.WriteFile(output, manifest, 0o644); err != nil {
+		return failf("writing %s: %v", output, err)
+	}
+	fmt.Printf("Wrote %d slash command(s) (%s) to %s\n", len(slashcommands.DefinitionsFor(guildID)), slashcommands.ScopeName(guildID), output)
+	return 0
+}
+
+// registerManifest makes the commands on Discord exactly what the manifest says, commands missing from it are removed
+func registerManifest(path, guildID string, dryRun bool) int {
+	if path == "" {
+		return failf("pass the manifest to register with -manifest")
+	}
+	defs, err := slashcommands.ReadManifest(path)
+	if err != nil {
+		return failf("%v", err)
+	}
+
+	s, appID, err := restSession()
+	if err != nil {
+		return failf("%v", err)
+	}
+
+	changes, err := slashcommands.Overwrite(s, appID, guildID, defs, dryRun)
+	if err != nil {
+		printChanges(changes, dryRun)
+		return failf("registering %s to %s commands: %v", path, slashcommands.ScopeName(guildID), err)
+	}
+
+	if !printChanges(changes, dryRun) {
+		fmt.Printf("Slash commands (%s) already match %s\n", slashcommands.ScopeName(guildID), path)
+		return 0
+	}
+	if dryRun {
+		fmt.Println("Dry run, nothing was changed")
+	} else {
+		fmt.Printf("Registered %s (%s)\n", path, slashcommands.ScopeName(guildID))
+	}
+	return 0
+}
+
+// scopeLabel describes where a command ends up for the list
+func scopeLabel(targets []string) string {
+	if len(targets) == 0 {
+		return "not registered"
+	}
+	names := make([]string, len(targets))
+	for i, guildID := range targets {
+		names[i] = slashcommands.ScopeName(guildID)
+	}
+	return strings.Join(names, ", ")
+}
+
+func adminLabel(admin bool) string {
+	if admin {
+		return "admin"
+	}
+	return ""
+}
+
+func aliasLabel(alias []string) string {
+	if len(alias) == 0 {
+		return ""
+	}
+	return "aliases: " + strings.Join(alias, ", ")
+}
@@ -0,0 +1,22 @@
+package cli
+
+import (
+	"template/bot"
+	"template/config"
+	"template/logging"
+	"template/util/templates"
+)
+
+// runBot loads everything and starts the bot, it blocks until the bot shuts down
+func runBot(args []string) int {
+	fs := newFlagSet("run")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	config.Load()
+	logging.Setup(config.Config.Logging)
+	templates.Load()
+
+	return bot.Start()
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"template/config"
+	"template/lifecycle"
+	"text/template"
+	"time"
+)
+
+// unitTemplate is the systemd unit install-service writes
+var unitTemplate = template.Must(template.New("unit").Parse(`[Unit]
+Description={{.Description}}
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=simple
+{{- if .User}}
+User={{.User}}
+{{- end}}
+WorkingDirectory={{.Dir}}
+ExecStart={{.Binary}} run
+Restart=on-failure
+RestartSec=5
+# SIGTERM starts the graceful shutdown, this gives it room to drain commands and run every shutdown step
+KillSignal=SIGTERM
+TimeoutStopSec={{.StopTimeout}}
+
+[Install]
+WantedBy=multi-user.target
+`))
+
+// installService writes a systemd unit for this binary, to stdout by default so it can be checked before installing
+func installService(args []string) int {
+	fs := newFlagSet("install-service")
+	name := fs.String("name", "discord-bot", "service name")
+	runAs := fs.String("user", "", "user the bot runs as (defaults to whoever runs this)")
+	output := fs.String("output", "", "where to write the unit, e.g. /etc/systemd/system/discord-bot.service (defaults to stdout)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	binary, err := os.Executable()
+	if err != nil {
+		return failf("finding the binary: %v", err)
+	}
+	if binary, err = filepath.EvalSymlinks(binary); err != nil {
+		return failf("finding the binary: %v", err)
+	}
+
+	// config and templates are read relative to the working directory so the service has to run from here
+	dir, err := os.Getwd()
+	if err != nil {
+		return failf("finding the working directory: %v", err)
+	}
+
+	if *runAs == "" {
+		if u, err := user.Current(); err == nil && u.Username != "root" {
+			*runAs = u.Username
+		}
+	}
+
+	// systemd kills us once TimeoutStopSec runs out, so it gets the longest shutdown can take plus a few seconds
+	// for the process to actually exit, otherwise the last steps (audit log, log file) would be the ones cut off
+	shutdown, description := 10, "Discord bot"
+	if c, err := config.Read(config.Path); err == nil {
+		if c.ShutdownTimeout > 0 {
+			shutdown = c.ShutdownTimeout
+		}
+		if c.Brand.Name != "" {
+			description = c.Brand.Name + " Discord bot"
+		}
+	}
+
+	var unit strings.Builder
+	err = unitTemplate.Execute(&unit, map[string]any{
+		"Description": description,
+		"User":        *runAs,
+		"Dir":         dir,
+		"Binary":      binary,
+		"StopTimeout": int(lifecycle.StopBudget(time.Duration(shutdown)*time.Second).Seconds()) + 5,
+	})
+	if err != nil {
+		return failf("%v", err)
+	}
+
+	if *output == "" {
+		fmt.Print(unit.String())
+		fmt.Fprintf(os.Stderr, "\nSave this as /etc/systemd/system/%s.service (or pass -output) and run:\n  systemctl daemon-reload && systemctl enable --now %s\n", *name, *name)
+		return 0
+	}
+
+	if err = os.WriteFile(*output, []byte(unit.String()), 0o644); err != nil {
+		return failf("writing %s: %v", *output, err)
+	}
+	fmt.Printf("Wrote %s, now run:\n  systemctl daemon-reload && systemctl enable --now %s\n", *output, *name)
+	return 0
+}
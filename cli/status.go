@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"template/config"
+	"template/monitor"
+	"template/util/timeutil"
+	"time"
+)
+
+// status asks a running bot how it is doing through /healthz and /readyz, it exits 1 when the bot is down or not ready
+func status(args []string) int {
+	fs := newFlagSet("status")
+	address := fs.String("address", "", "monitor address to query (defaults to http_address from the config)")
+	timeout := fs.Duration("timeout", 5*time.Second, "how long to wait for an answer")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *address == "" {
+		c, err := config.Read(config.Path)
+		if err != nil {
+			return failf("%v", err)
+		}
+		*address = c.HTTPAddress
+	}
+	if *address == "" {
+		return failf("http_address is not set in the config so the bot has nothing to ask, set it or pass -address")
+	}
+
+	base := "http://" + localAddress(*address)
+	client := &http.Client{Timeout: *timeout}
+
+	var health struct {
+		Status        string `json:"status"`
+		UptimeSeconds int64  `json:"uptime_seconds"`
+	}
+	if err := getJSON(client, base+"/healthz", &health); err != nil {
+		fmt.Println("✗ Bot is not running (or the monitor listener is off)")
+		return failf("%v", err)
+	}
+
+	var readiness monitor.Readiness
+	if err := getJSON(client, base+"/readyz", &readiness); err != nil {
+		return failf("%v", err)
+	}
+
+	uptime := timeutil.Humanize(time.Duration(health.UptimeSeconds)*time.Second, timeutil.Options{Precision: 2, Smallest: timeutil.Second})
+	if readiness.Ready {
+		fmt.Printf("✓ Bot is running and ready (up %s)\n", uptime)
+	} else {
+		fmt.Printf("✗ Bot is running but not ready (up %s)\n", uptime)
+	}
+
+	names := make([]string, 0, len(readiness.Checks))
+	for name := range readiness.Checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		check := readiness.Checks[name]
+		mark := "✓"
+		if !check.OK {
+			mark = "✗"
+		}
+		fmt.Printf("  %s %-10s %s\n", mark, name, check.Detail)
+	}
+
+	if !readiness.Ready {
+		return 1
+	}
+	return 0
+}
+
+// localAddress turns a listen address into one we can connect to, ":9090" and "0.0.0.0:9090" listen everywhere
+// but we want to ask the copy running on this machine
+func localAddress(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// getJSON fetches a monitor endpoint, /readyz answers 503 when not ready but still has a body so we decode it either way
+func getJSON(client *http.Client, url string, v any) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return fmt.Errorf("%s answered %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
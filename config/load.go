@@ -2,6 +2,7 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 
 	"github.com/yourpov/logrite"
@@ -12,25 +13,166 @@ type BrandConfig struct {
 	Icon string `json:"icon"`
 }
 
+// ThemeConfig controls the colors and default branding applied to every themed embed
+type ThemeConfig struct {
+	// Colors are hex colors (#ffffff) for each kind of embed, anything left empty uses the built in default
+	Colors ThemeColors `json:"colors"`
+	// Footer is the default footer text, leave empty to use the brand name
+	Footer string `json:"footer"`
+	// FooterIcon is the default footer icon, leave empty to use the brand icon
+	FooterIcon string `json:"footer_icon"`
+	// Thumbnail is an optional thumbnail added to every themed embed
+	Thumbnail string `json:"thumbnail"`
+	// Author is an optional author block added to every themed embed
+	Author ThemeAuthor `json:"author"`
+}
+
+// ThemeColors holds the hex color for each embed kind
+type ThemeColors struct {
+	Primary string `json:"primary"`
+	Success string `json:"success"`
+	Warning string `json:"warning"`
+	Error   string `json:"error"`
+	Info    string `json:"info"`
+}
+
+// ThemeAuthor is the default author block for themed embeds
+type ThemeAuthor struct {
+	Name string `json:"name"`
+	Icon string `json:"icon"`
+	URL  string `json:"url"`
+}
+
+// LoggingConfig controls how and where we log
+type LoggingConfig struct {
+	// Format is "console" for colored output (good for development) or "json" for structured JSON lines
+	Format string `json:"format"`
+	// Level is the minimum level to log (debug, info, warn, error)
+	Level string `json:"level"`
+	// File is where JSON logs are written, leave empty to write them to stdout
+	File string `json:"file"`
+	// MaxSizeMB is how big the log file can get before it is rotated, 0 turns rotation off
+	MaxSizeMB int `json:"max_size_mb"`
+	// MaxBackups is how many rotated log files we keep around
+	MaxBackups int `json:"max_backups"`
+}
+
+// AuditConfig controls the command audit log
+type AuditConfig struct {
+	// Enabled when true, records every command invocation to the audit log
+	Enabled bool `json:"enabled"`
+	// File is where the audit log is written (JSON lines), defaults to ./data/audit.jsonl
+	File string `json:"file"`
+	// ChannelID is an optional Discord channel that admin command invocations are mirrored to
+	ChannelID string `json:"channel_id"`
+	// MirrorAll when true, mirrors every command to the channel instead of just admin ones
+	MirrorAll bool `json:"mirror_all"`
+}
+
+// IntentsConfig turns on the gateway intents for optional features, prefix commands get theirs automatically
+type IntentsConfig struct {
+	// Members when true, receives member join/leave/update events (privileged)
+	Members bool `json:"members"`
+	// Presences when true, receives presence updates (privileged)
+	Presences bool `json:"presences"`
+	// Reactions when true, receives reaction add/remove events
+	Reactions bool `json:"reactions"`
+	// DirectMessages when true, receives DMs so prefix commands (and reactions) work in them
+	DirectMessages bool `json:"direct_messages"`
+	// VoiceStates when true, receives voice state updates
+	VoiceStates bool `json:"voice_states"`
+	// Add is a list of extra intents to request by name (e.g. "guild_invites")
+	Add []string `json:"add"`
+	// Remove is a list of intents to never request by name, this wins over everything else
+	Remove []string `json:"remove"`
+}
+
+// PresenceConfig controls the bot's presence (the "Playing ..." line under its name)
+type PresenceConfig struct {
+	// Enabled when true, sets the presence and rotates through the activities
+	Enabled bool `json:"enabled"`
+	// Status is online, idle, dnd or invisible
+	Status string `json:"status"`
+	// Interval is how many seconds each activity shows before rotating to the next one, defaults to 60
+	Interval int `json:"interval"`
+	// Activities are rotated through in order
+	Activities []PresenceActivity `json:"activities"`
+}
+
+// PresenceActivity is a single activity, Text can use {{.Guilds}}, {{.Shards}}, {{.Uptime}}, {{.Prefix}} and {{.Brand.Name}}
+type PresenceActivity struct {
+	// Type is playing, watching, listening, competing or custom
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// CommandRule limits where and by whom a command can be used, deny lists win over allow lists and an empty
+// allow list allows everything
+type CommandRule struct {
+	AllowChannels   []string `json:"allow_channels,omitempty"`
+	DenyChannels    []string `json:"deny_channels,omitempty"`
+	AllowCategories []string `json:"allow_categories,omitempty"`
+	DenyCategories  []string `json:"deny_categories,omitempty"`
+	AllowRoles      []string `json:"allow_roles,omitempty"`
+	DenyRoles       []string `json:"deny_roles,omitempty"`
+}
+
 type cfg struct {
 	// Token is the bot token from the Discord Developer Portal
 	Token string `json:"token"`
 	// Prefix is the command prefix for prefix commands
 	Prefix string `json:"prefix"`
+	// Prefixes are extra prefixes that work next to Prefix (e.g. ["!", "bot "])
+	Prefixes []string `json:"prefixes"`
+	// MentionPrefix when true, lets users run prefix commands by mentioning the bot (@Bot help)
+	MentionPrefix bool `json:"mention_prefix"`
+	// PrefixCaseInsensitive when true, matches prefixes in any case (so "Bot " also works for "bot ")
+	PrefixCaseInsensitive bool `json:"prefix_case_insensitive"`
+	// IgnoreUnknownCommands when true, says nothing when someone uses a prefix command we dont have (handy when bots share a prefix)
+	IgnoreUnknownCommands bool `json:"ignore_unknown_commands"`
 	// Brand contains branding information for the bot, such as name and icon URL
 	Brand BrandConfig `json:"brand"`
+	// Theme controls embed colors and the default footer/thumbnail/author
+	Theme ThemeConfig `json:"theme"`
 	// GuildID is the ID of the guild (server) to register commands in, leave empty to register globally
 	GuildID string `json:"guild_id"`
+	// DevGuildID is the staging guild for slash commands scoped to "dev", leave empty to use guild_id
+	DevGuildID string `json:"dev_guild_id"`
+	// CommandScopes overrides where slash commands are registered by name, each one is a list of "global", "dev" or guild IDs
+	CommandScopes map[string][]string `json:"command_scopes"`
+	// CommandRules limits where and by whom commands can be used by name, admins can add more at runtime with .command
+	CommandRules map[string]CommandRule `json:"command_rules"`
 	// AuthenticatedIds is a list of user IDs that are authorized to use admin-only commands
 	AuthenticatedIds []string `json:"authenticated_ids"`
 	// PrefixEnabled when true, enables prefix commands
 	PrefixEnabled bool `json:"prefix_enabled"`
 	// SlashEnabled when true, enables slash commands
 	SlashEnabled bool `json:"slash_enabled"`
+	// Logging controls the log format, level and output file
+	Logging LoggingConfig `json:"logging"`
+	// SettingsFile is where settings changed at runtime (like disabled commands) are saved, defaults to ./data/settings.json
+	SettingsFile string `json:"settings_file"`
+	// Audit controls the command audit log
+	Audit AuditConfig `json:"audit"`
+	// Presence controls the bot's status and rotating activities
+	Presence PresenceConfig `json:"presence"`
+	// Intents controls which gateway intents we request
+	Intents IntentsConfig `json:"intents"`
+	// ShardCount is how many shards to run, leave at 0 to use the count Discord recommends
+	ShardCount int `json:"shard_count"`
+	// HTTPAddress is the address for the metrics and health probe listener (e.g. "127.0.0.1:9090"), leave empty to disable it
+	HTTPAddress string `json:"http_address"`
+	// CommandTimeout is how many seconds a command can run before its context is canceled, defaults to 60
+	CommandTimeout int `json:"command_timeout"`
+	// ShutdownTimeout is how many seconds we wait for running commands (and each shutdown step) before giving up, defaults to 10
+	ShutdownTimeout int `json:"shutdown_timeout"`
 	// DeRegisterCommandsAfterRestart when true, removes all slash commands from Discord when the bot shuts down
 	DeRegisterCommandsAfterRestart bool `json:"deregister_commands_after_restart"`
 }
 
+// Path is where the config file lives, relative to the directory the bot runs in
+const Path = "./config/config.json"
+
 var (
 	// Config holds the bot configuration, loaded from config.json so do (config.) when anything from it
 	Config *cfg
@@ -38,17 +180,29 @@ var (
 
 // Load reads the configuration file and unmarshals it into the Config variable
 func Load() {
-	f, err := os.ReadFile("./config/config.json")
+	c, err := Read(Path)
 	if err != nil {
-		// if we cant read the file, we want to stop
-		logrite.Error("Failed to read config file: %v", err)
+		// if we cant read or parse the file, we want to stop
+		logrite.Error("Failed to load config file: %v", err)
+		// we exit as we cant run without a valid config you silly silly person you kek
 		os.Exit(1)
 	}
+	Config = c
+}
 
-	if err = json.Unmarshal(f, &Config); err != nil {
-		// if we cant parse the file, we also want to stop
-		logrite.Error("Failed to parse config file: %v", err)
-		// we exit as we cant run without a valid config you silly silly person you kek
-		os.Exit(1)
+// Read reads and parses a config file without touching Config, check-config uses it to report errors instead of exiting
+func Read(path string) (*cfg, error) {
+	f, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c *cfg
+	if err = json.Unmarshal(f, &c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if c == nil {
+		return nil, fmt.Errorf("%s is empty", path)
 	}
+	return c, nil
 }
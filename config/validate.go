@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Validate looks for mistakes in a config that would only show up once the bot is running, every problem is returned
+// as its own line so check-config can list them all at once instead of making you fix them one by one
+// (presence and intent names and theme colors are checked by their own packages since that is where they get parsed)
+func (c *cfg) Validate() []string {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if strings.TrimSpace(c.Token) == "" {
+		add("token is empty")
+	}
+	if !c.PrefixEnabled && !c.SlashEnabled {
+		add("prefix_enabled and slash_enabled are both off, the bot would not respond to anything")
+	}
+	if c.PrefixEnabled && c.Prefix == "" {
+		add("prefix is empty but prefix_enabled is on")
+	}
+	for i, prefix := range c.Prefixes {
+		if strings.TrimSpace(prefix) == "" {
+			add("prefixes[%d] is empty, it would make every message a command", i)
+		}
+	}
+	if c.GuildID != "" && !isSnowflake(c.GuildID) {
+		add("guild_id %q is not a valid ID", c.GuildID)
+	}
+	if c.DevGuildID != "" && !isSnowflake(c.DevGuildID) {
+		add("dev_guild_id %q is not a valid ID", c.DevGuildID)
+	}
+	for _, name := range sortedKeys(c.CommandScopes) {
+		for _, target := range c.CommandScopes[name] {
+			if target != "global" && target != "dev" && !isSnowflake(target) {
+				add("command_scopes.%s has %q, use \"global\", \"dev\" or a guild ID", name, target)
+			}
+		}
+	}
+	for _, name := range sortedKeys(c.CommandRules) {
+		rule := c.CommandRules[name]
+		for _, list := range []struct {
+			key string
+			ids []string
+		}{
+			{"allow_channels", rule.AllowChannels},
+			{"deny_channels", rule.DenyChannels},
+			{"allow_categories", rule.AllowCategories},
+			{"deny_categories", rule.DenyCategories},
+			{"allow_roles", rule.AllowRoles},
+			{"deny_roles", rule.DenyRoles},
+		} {
+			for _, id := range list.ids {
+				if !isSnowflake(id) {
+					add("command_rules.%s.%s has %q which is not a valid ID", name, list.key, id)
+				}
+			}
+		}
+	}
+	for _, id := range c.AuthenticatedIds {
+		if !isSnowflake(id) {
+			add("authenticated_ids has %q which is not a valid user ID", id)
+		}
+	}
+	if c.Audit.ChannelID != "" && !isSnowflake(c.Audit.ChannelID) {
+		add("audit.channel_id %q is not a valid ID", c.Audit.ChannelID)
+	}
+
+	switch strings.ToLower(c.Logging.Format) {
+	case "", "console", "json":
+	default:
+		add("logging.format %q should be console or json", c.Logging.Format)
+	}
+	switch strings.ToLower(c.Logging.Level) {
+	case "", "debug", "info", "warn", "warning", "error":
+	default:
+		add("logging.level %q should be debug, info, warn or error", c.Logging.Level)
+	}
+
+	if c.ShardCount < 0 {
+		add("shard_count cant be negative")
+	}
+	if c.CommandTimeout < 0 {
+		add("command_timeout cant be negative")
+	}
+	if c.ShutdownTimeout < 0 {
+		add("shutdown_timeout cant be negative")
+	}
+
+	return problems
+}
+
+// isSnowflake reports whether s looks like a Discord ID
+func isSnowflake(s string) bool {
+	if len(s) < 15 || len(s) > 21 {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// sortedKeys returns the keys of a map in order so problems are always listed the same way
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
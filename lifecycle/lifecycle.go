@@ -0,0 +1,176 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"template/logging"
+	"time"
+)
+
+// Shutdown hook orders, lower runs first, subsystems can pick anything in between
+const (
+	OrderDiscord    = 10 // anything that still needs the gateway/REST session (deregistering commands, closing menus)
+	OrderSession    = 20 // closing the discord session itself
+	OrderSchedulers = 30 // timers and background workers
+	OrderServers    = 40 // HTTP listeners
+	OrderStorage    = 50 // flushing files to disk
+	OrderLogging    = 90 // logs go last so everything above can still log
+)
+
+// DefaultDrainTimeout is how long we wait for running commands when the config doesnt say
+const DefaultDrainTimeout = 10 * time.Second
+
+// LateHookWindow is the extra time hooks still get once the hook deadline passed, so the quick ones at the end
+// (closing the audit log and the log file) still run when an earlier hook got stuck
+const LateHookWindow = 5 * time.Second
+
+// StopBudget is the longest Shutdown can take with a drain timeout: the drain, the hooks together and the late window
+// anything that kills us after a stop signal (systemd, docker) should wait at least this long
+func StopBudget(drainTimeout time.Duration) time.Duration {
+	if drainTimeout <= 0 {
+		drainTimeout = DefaultDrainTimeout
+	}
+	return 2*drainTimeout + LateHookWindow
+}
+
+// Hook is a function that runs during shutdown
+type Hook struct {
+	Name  string
+	Order int
+	Fn    func(ctx context.Context) error
+}
+
+var (
+	lock     sync.Mutex
+	inflight sync.WaitGroup
+	hooks    []Hook
+	draining bool
+
+	// ctx is handed to every command, it gets canceled once the drain timeout runs out (or draining finishes)
+	ctx, cancel = context.WithCancel(context.Background())
+
+	// stopping is closed as soon as shutdown starts so long running work knows to wrap up
+	stopping = make(chan struct{})
+)
+
+// Context returns the shutdown context, its canceled when the bot stops waiting for commands
+func Context() context.Context {
+	return ctx
+}
+
+// Stopping returns a channel that is closed as soon as shutdown starts
+func Stopping() <-chan struct{} {
+	return stopping
+}
+
+// Track marks a command as running, call done when it finishes
+// ok is false once we are shutting down, the command shouldnt run at all then
+func Track() (done func(), ok bool) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if draining {
+		return func() {}, false
+	}
+	inflight.Add(1)
+	return inflight.Done, true
+}
+
+// OnShutdown registers a hook that runs during shutdown, hooks run in order (lowest first)
+func OnShutdown(name string, order int, fn func(ctx context.Context) error) {
+	lock.Lock()
+	defer lock.Unlock()
+	hooks = append(hooks, Hook{Name: name, Order: order, Fn: fn})
+}
+
+// WaitForSignal blocks until we get SIGINT or SIGTERM
+func WaitForSignal() os.Signal {
+	sc := make(chan os.Signal, 1)
+	// we want to listen for termination signals to gracefully shutdown
+	// this is especially important if you want to deregister commands on shutdown
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(sc)
+	return <-sc
+}
+
+// Shutdown stops new commands, waits up to drainTimeout for running ones to finish and then runs every hook in order
+// it returns the exit code the process should exit with (0 when everything went cleanly)
+func Shutdown(drainTimeout time.Duration) int {
+	if drainTimeout <= 0 {
+		drainTimeout = DefaultDrainTimeout
+	}
+
+	lock.Lock()
+	if draining {
+		lock.Unlock()
+		return 0
+	}
+	draining = true
+	close(stopping)
+	ordered := append([]Hook(nil), hooks...)
+	lock.Unlock()
+
+	code := 0
+
+	// phase 1: wait for commands that are still running
+	logging.Warn("Shutting down, waiting up to %s for running commands...", drainTimeout)
+	drained := make(chan struct{})
+	go func() {
+		inflight.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		logging.Info("All running commands finished")
+	case <-time.After(drainTimeout):
+		logging.Warn("Drain timeout reached, canceling commands that are still running")
+		code = 1
+	}
+	cancel()
+
+	// phase 2: run the hooks in order, together they get drainTimeout so shutdown as a whole stays within StopBudget
+	// once that is used up the hooks left split LateHookWindow evenly, so one stuck hook cant hang the process or starve the rest
+	sort.SliceStable(ordered, func(a, b int) bool { return ordered[a].Order < ordered[b].Order })
+	hooksDeadline := time.Now().Add(drainTimeout)
+	var late time.Duration
+	for n, hook := range ordered {
+		deadline := hooksDeadline
+		if time.Now().After(deadline) {
+			if late == 0 {
+				late = LateHookWindow / time.Duration(len(ordered)-n)
+			}
+			deadline = time.Now().Add(late)
+		}
+		if err := runHook(hook, deadline); err != nil {
+			logging.Error("Shutdown hook '%s' failed: %v", hook.Name, err)
+			code = 1
+		}
+	}
+
+	return code
+}
+
+// runHook runs a shutdown hook and stops waiting for it once the deadline passes
+// plenty of hooks dont look at their context (closing sessions etc) so we cant just rely on them returning,
+// one that times out is left running in the background and we move on to the next
+func runHook(hook Hook, deadline time.Time) error {
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	// buffered so a hook that finishes after we gave up doesnt block forever
+	done := make(chan error, 1)
+	go func() { done <- hook.Fn(ctx) }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("ran out of time")
+	}
+}
@@ -0,0 +1,252 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"template/config"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/yourpov/logrite"
+)
+
+// Fields are extra key/values attached to a log line
+type Fields map[string]any
+
+// Logger logs with a set of fields attached to every line
+type Logger struct {
+	fields Fields
+}
+
+// CommandEntry is everything we log about a single command execution
+type CommandEntry struct {
+	InvocationID string
+	Name         string
+	Type         string // prefix or slash
+	UserID       string
+	GuildID      string
+	ChannelID    string
+	Duration     time.Duration
+	Outcome      string
+}
+
+var (
+	lock       sync.RWMutex
+	jsonLogger *slog.Logger // nil when we are in console mode
+	level      = slog.LevelInfo
+	output     io.Closer // the log file, if we opened one
+	root       = &Logger{}
+)
+
+// Setup switches logging to whatever the config asks for, console mode (colored logrite output) is the default
+func Setup(c config.LoggingConfig) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	level = parseLevel(c.Level)
+
+	if !strings.EqualFold(c.Format, "json") {
+		jsonLogger = nil
+		return
+	}
+
+	var w io.Writer = os.Stdout
+	if c.File != "" {
+		f, err := openRotatingFile(c.File, c.MaxSizeMB, c.MaxBackups)
+		if err != nil {
+			// we can still log to stdout so this isnt worth dying over
+			logrite.Error("Failed to open log file %s, logging to stdout instead: %v", c.File, err)
+		} else {
+			w = f
+			output = f
+		}
+	}
+
+	jsonLogger = slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// Close flushes and closes the log file if we have one open
+// anything logged after that goes to stderr instead of a closed file, so late shutdown errors still show up somewhere
+func Close() {
+	lock.Lock()
+	defer lock.Unlock()
+	if output != nil {
+		output.Close()
+		output = nil
+		jsonLogger = slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	}
+}
+
+// With returns a logger that adds fields to every line
+func With(fields Fields) *Logger {
+	return root.With(fields)
+}
+
+// With returns a copy of the logger with more fields added
+func (l *Logger) With(fields Fields) *Logger {
+	merged := make(Fields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &Logger{fields: merged}
+}
+
+// Debug logs a debug message
+func (l *Logger) Debug(msg string, args ...any) {
+	l.log(slog.LevelDebug, "debug", fmt.Sprintf(msg, args...), logrite.Debug)
+}
+
+// Info logs an info message
+func (l *Logger) Info(msg string, args ...any) {
+	l.log(slog.LevelInfo, "info", fmt.Sprintf(msg, args...), logrite.Info)
+}
+
+// Success logs a success message (info level in JSON mode)
+func (l *Logger) Success(msg string, args ...any) {
+	l.log(slog.LevelInfo, "success", fmt.Sprintf(msg, args...), logrite.Success)
+}
+
+// Warn logs a warning
+func (l *Logger) Warn(msg string, args ...any) {
+	l.log(slog.LevelWarn, "warn", fmt.Sprintf(msg, args...), logrite.Warn)
+}
+
+// Error logs an error
+func (l *Logger) Error(msg string, args ...any) {
+	l.log(slog.LevelError, "error", fmt.Sprintf(msg, args...), logrite.Error)
+}
+
+// log writes a line in whichever mode we are in, console mode goes through the matching logrite function
+func (l *Logger) log(lvl slog.Level, tag, msg string, console func(string, ...interface{})) {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	if lvl < level {
+		return
+	}
+
+	if jsonLogger != nil {
+		jsonLogger.LogAttrs(context.Background(), lvl, msg, l.attrs(slog.String("tag", tag))...)
+		return
+	}
+	console("%s", msg+l.consoleFields())
+}
+
+// attrs turns our fields into slog attributes
+func (l *Logger) attrs(extra ...slog.Attr) []slog.Attr {
+	attrs := append([]slog.Attr(nil), extra...)
+	for _, k := range sortedFields(l.fields) {
+		attrs = append(attrs, slog.Any(k, l.fields[k]))
+	}
+	return attrs
+}
+
+// consoleFields formats fields like " user=123 guild=456" for the console
+func (l *Logger) consoleFields() string {
+	if len(l.fields) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for _, k := range sortedFields(l.fields) {
+		fmt.Fprintf(&b, " %s=%v", k, l.fields[k])
+	}
+	return color.New(color.FgHiBlack).Sprint(b.String())
+}
+
+// Debug logs a debug message
+func Debug(msg string, args ...any) { root.Debug(msg, args...) }
+
+// Info logs an info message
+func Info(msg string, args ...any) { root.Info(msg, args...) }
+
+// Success logs a success message
+func Success(msg string, args ...any) { root.Success(msg, args...) }
+
+// Warn logs a warning
+func Warn(msg string, args ...any) { root.Warn(msg, args...) }
+
+// Error logs an error
+func Error(msg string, args ...any) { root.Error(msg, args...) }
+
+// Custom logs a custom tagged line like logrite.Custom, JSON mode logs it at info level with the tag as a field
+func Custom(emoji, tag, format string, fg, bg color.Attribute, args ...any) {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	if slog.LevelInfo < level {
+		return
+	}
+	if jsonLogger != nil {
+		jsonLogger.LogAttrs(context.Background(), slog.LevelInfo, fmt.Sprintf(format, args...), slog.String("tag", strings.ToLower(tag)))
+		return
+	}
+	logrite.Custom(emoji, tag, format, fg, bg, args...)
+}
+
+// CommandExecuted logs a command execution with all its context fields
+func CommandExecuted(e CommandEntry) {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	lvl := slog.LevelInfo
+	if e.Outcome == "error" {
+		lvl = slog.LevelError
+	}
+	if lvl < level {
+		return
+	}
+
+	if jsonLogger != nil {
+		jsonLogger.LogAttrs(context.Background(), lvl, "command executed",
+			slog.String("tag", "command"),
+			slog.String("invocation_id", e.InvocationID),
+			slog.String("command", e.Name),
+			slog.String("type", e.Type),
+			slog.String("user_id", e.UserID),
+			slog.String("guild_id", e.GuildID),
+			slog.String("channel_id", e.ChannelID),
+			slog.Float64("duration_ms", float64(e.Duration.Microseconds())/1000),
+			slog.String("outcome", e.Outcome),
+		)
+		return
+	}
+
+	// console mode keeps the familiar logrite command line, anything that didnt go well gets a warning instead
+	if e.Outcome == "success" {
+		logrite.Cmd(e.Type+":"+e.Name, e.Duration.Round(time.Microsecond).String(), e.UserID)
+		return
+	}
+	logrite.Warn("'%s:%s' by %s finished with outcome %s (guild=%s channel=%s)", e.Type, e.Name, e.UserID, e.Outcome, e.GuildID, e.ChannelID)
+}
+
+// parseLevel turns a level name from the config into a slog level
+func parseLevel(s string) slog.Level {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// sortedFields returns the field keys in order so lines always look the same
+func sortedFields(fields Fields) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
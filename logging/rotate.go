@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// rotatingFile is an io.Writer that rolls the log file over once it gets too big
+// app.log becomes app.log.1, app.log.1 becomes app.log.2 and so on until MaxBackups
+type rotatingFile struct {
+	lock       sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// openRotatingFile opens (or creates) the log file, maxSizeMB <= 0 turns rotation off
+func openRotatingFile(path string, maxSizeMB, maxBackups int) (*rotatingFile, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	r := &rotatingFile{path: path, maxSize: int64(maxSizeMB) * 1024 * 1024, maxBackups: maxBackups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Write writes p to the file, rotating first if it wouldnt fit
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.maxSize > 0 && r.size+int64(len(p)) > r.maxSize && r.size > 0 {
+		if err := r.rotate(); err != nil {
+			// we still want the line written somewhere so we keep going with the old file, the size starts over
+			// so we dont try (and shift the backups along) again on every single line
+			fmt.Fprintf(os.Stderr, "failed to rotate log file: %v\n", err)
+			r.size = 0
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close closes the current log file
+func (r *rotatingFile) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.file.Close()
+}
+
+// open opens the log file for appending (caller must hold the lock)
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file = f
+	r.size = info.Size()
+	return nil
+}
+
+// rotate shifts the backups along and starts a fresh file (caller must hold the lock)
+// the old file stays open until the new one is, so if opening fails we still have something to write to
+func (r *rotatingFile) rotate() error {
+	old := r.file
+
+	// the oldest backup falls off the end
+	if r.maxBackups > 0 {
+		os.Remove(fmt.Sprintf("%s.%d", r.path, r.maxBackups))
+		for i := r.maxBackups - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+		}
+		os.Rename(r.path, r.path+".1")
+	} else {
+		os.Remove(r.path)
+	}
+
+	if err := r.open(); err != nil {
+		return err
+	}
+	// the new file is already in use so theres nothing useful to do if closing the old one fails
+	old.Close()
+	return nil
+}
@@ -1,17 +1,11 @@
 package main
 
 import (
-	"template/bot"
-	"template/config"
+	"os"
+	"template/cli"
 )
 
-// init loads the config and commands
-func init() {
-	config.Load()
-
-}
-
-// main starts the bot
+// main runs the subcommand we were started with (the bot itself when there is none) and exits with its code
 func main() {
-	bot.Start()
+	os.Exit(cli.Run(os.Args[1:]))
 }
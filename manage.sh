@@ -76,8 +76,20 @@ build_bot() {
     rm -f "$BIN_NAME"
   fi
   
+  # stamp the version, commit and build date into the binary so .version / /version can tell which deploy is live
+  local version commit date dirty
+  version="$(git describe --tags --always 2>/dev/null || echo dev)"
+  commit="$(git rev-parse HEAD 2>/dev/null || echo "")"
+  date="$(date -u '+%Y-%m-%dT%H:%M:%SZ')"
+  dirty="false"
+  if [ -n "$(git status --porcelain 2>/dev/null)" ]; then
+    dirty="true"
+  fi
+  local ldflags="-X template/buildinfo.Version=$version -X template/buildinfo.Commit=$commit -X template/buildinfo.Date=$date -X template/buildinfo.Dirty=$dirty"
+  log_info "Version: $version ($commit, dirty=$dirty)"
+
   log_build "Compiling Go source code..."
-  if ! go build -v -o "$BIN_NAME" 2>&1 | tee "$BUILD_LOG"; then
+  if ! go build -v -ldflags "$ldflags" -o "$BIN_NAME" 2>&1 | tee "$BUILD_LOG"; then
     log_error "Build failed! Check $BUILD_LOG for details"
     write_status "Build failed - compilation error"
     echo
@@ -0,0 +1,90 @@
+package monitor
+
+import (
+	"strconv"
+	"sync"
+	"template/shards"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Command outcomes used for the outcome label
+const (
+	OutcomeSuccess      = "success"
+	OutcomeError        = "error"
+	OutcomeUnauthorized = "unauthorized"
+	OutcomeNotFound     = "not_found"
+	OutcomeDisabled     = "disabled"
+	OutcomeForbidden    = "forbidden" // blocked by a channel, category or role rule
+)
+
+// these are the bot metrics, the dispatchers in bot/start.go feed the command ones
+var (
+	CommandsTotal   = NewCounterVec("bot_commands_total", "Commands executed by name, type and outcome.", "command", "type", "outcome")
+	CommandDuration = NewHistogramVec("bot_command_duration_seconds", "How long command handlers took to run.", DefaultBuckets, "command", "type")
+	Reconnects      = NewCounterVec("bot_gateway_reconnects_total", "Times a shard's gateway connection was re-established.", "shard")
+	RateLimits      = NewCounterVec("bot_rest_ratelimit_hits_total", "REST requests that hit a Discord rate limit.")
+)
+
+func init() {
+	register(runtimeMetrics{})
+}
+
+// ObserveCommand records a command execution
+func ObserveCommand(name, kind, outcome string, took time.Duration) {
+	CommandsTotal.Inc(name, kind, outcome)
+	if outcome == OutcomeSuccess || outcome == OutcomeError {
+		// only commands that actually ran get a latency, rejected ones would just drag the numbers down
+		CommandDuration.Observe(took.Seconds(), name, kind)
+	}
+}
+
+// TrackShards registers the gauges and handlers that read from the shard sessions, call it after shards.Setup
+func TrackShards() {
+	NewGaugeFunc("bot_shards", "Number of shards the bot is running.", func() float64 {
+		return float64(shards.Count())
+	})
+
+	NewGaugeVecFunc("bot_shard_up", "Whether a shard is connected and READY (1) or not (0).", []string{"shard"}, func(set func(float64, ...string)) {
+		for _, st := range shards.Statuses() {
+			up := 0.0
+			if st.Ready {
+				up = 1
+			}
+			set(up, strconv.Itoa(st.ID))
+		}
+	})
+
+	NewGaugeVecFunc("bot_gateway_latency_seconds", "Latency between the last gateway heartbeat and its ACK.", []string{"shard"}, func(set func(float64, ...string)) {
+		for _, st := range shards.Statuses() {
+			set(st.Latency.Seconds(), strconv.Itoa(st.ID))
+		}
+	})
+
+	NewGaugeVecFunc("bot_guilds", "Number of guilds the bot is in.", []string{"shard"}, func(set func(float64, ...string)) {
+		for _, st := range shards.Statuses() {
+			set(float64(st.Guilds), strconv.Itoa(st.ID))
+		}
+	})
+
+	// the first connect of each shard is just us starting up, every one after that is a reconnect
+	var (
+		connectedLock sync.Mutex
+		connected     = make(map[int]bool)
+	)
+	shards.AddHandler(func(s *discordgo.Session, _ *discordgo.Connect) {
+		connectedLock.Lock()
+		again := connected[s.ShardID]
+		connected[s.ShardID] = true
+		connectedLock.Unlock()
+
+		if again {
+			Reconnects.Inc(strconv.Itoa(s.ShardID))
+		}
+	})
+
+	shards.AddHandler(func(_ *discordgo.Session, _ *discordgo.RateLimit) {
+		RateLimits.Inc()
+	})
+}
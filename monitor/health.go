@@ -0,0 +1,154 @@
+package monitor
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"template/lifecycle"
+	"template/shards"
+	"time"
+)
+
+// HeartbeatMaxAge is how old the last heartbeat ACK can be before we stop calling ourselves ready
+// discord asks for a heartbeat roughly every 41 seconds so this gives it a couple of chances
+const HeartbeatMaxAge = 2 * time.Minute
+
+// health keeps track of everything /readyz reports on
+var health = struct {
+	lock           sync.RWMutex
+	commandsSynced bool
+	slashDisabled  bool
+}{}
+
+// Check is the result of a single readiness check
+type Check struct {
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail"`
+}
+
+// Readiness is what /readyz responds with
+type Readiness struct {
+	Ready  bool             `json:"ready"`
+	Checks map[string]Check `json:"checks"`
+}
+
+func init() {
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		// if we can answer this at all the process is alive
+		writeJSON(w, http.StatusOK, map[string]any{
+			"status":         "ok",
+			"uptime_seconds": int64(time.Since(startTime).Seconds()),
+		})
+	})
+
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		readiness := Ready()
+		status := http.StatusOK
+		if !readiness.Ready {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, readiness)
+	})
+}
+
+// SetCommandsSynced records whether our slash commands are registered with Discord
+func SetCommandsSynced(synced bool) {
+	health.lock.Lock()
+	defer health.lock.Unlock()
+	health.commandsSynced = synced
+}
+
+// SetSlashDisabled tells the readiness check not to wait on slash commands
+func SetSlashDisabled(disabled bool) {
+	health.lock.Lock()
+	defer health.lock.Unlock()
+	health.slashDisabled = disabled
+}
+
+// Ready runs every readiness check
+func Ready() Readiness {
+	health.lock.RLock()
+	defer health.lock.RUnlock()
+
+	checks := make(map[string]Check)
+
+	statuses := shards.Statuses()
+	checks["gateway"] = gatewayCheck(statuses)
+
+	switch {
+	case health.slashDisabled:
+		checks["commands"] = Check{true, "slash commands are disabled"}
+	case health.commandsSynced:
+		checks["commands"] = Check{true, "slash commands registered"}
+	default:
+		checks["commands"] = Check{false, "slash commands not registered yet"}
+	}
+
+	checks["heartbeat"] = heartbeatCheck(statuses)
+
+	// load balancers should stop sending us anything the moment shutdown starts
+	select {
+	case <-lifecycle.Stopping():
+		checks["shutdown"] = Check{false, "shutting down"}
+	default:
+		checks["shutdown"] = Check{true, "running"}
+	}
+
+	readiness := Readiness{Ready: true, Checks: checks}
+	for _, check := range checks {
+		if !check.OK {
+			readiness.Ready = false
+		}
+	}
+	return readiness
+}
+
+// gatewayCheck makes sure every shard got READY and hasnt disconnected since
+func gatewayCheck(statuses []shards.Status) Check {
+	if len(statuses) == 0 {
+		return Check{false, "no shards"}
+	}
+
+	var waiting []string
+	for _, st := range statuses {
+		if !st.Ready {
+			waiting = append(waiting, strconv.Itoa(st.ID))
+		}
+	}
+	if len(waiting) > 0 {
+		return Check{false, "waiting for READY on shard " + strings.Join(waiting, ", ")}
+	}
+	return Check{true, strconv.Itoa(len(statuses)) + " shard(s) ready"}
+}
+
+// heartbeatCheck makes sure discord ACKed one of our heartbeats recently on every shard
+func heartbeatCheck(statuses []shards.Status) Check {
+	if len(statuses) == 0 {
+		return Check{false, "no shards"}
+	}
+
+	// the oldest ACK is the one that matters, one stuck shard means some guilds arent being served
+	var oldest time.Duration
+	for _, st := range statuses {
+		if st.LastHeartbeatAck.IsZero() {
+			return Check{false, "no heartbeat ACK yet on shard " + strconv.Itoa(st.ID)}
+		}
+		if age := time.Since(st.LastHeartbeatAck); age > oldest {
+			oldest = age
+		}
+	}
+
+	if oldest > HeartbeatMaxAge {
+		return Check{false, "last heartbeat ACK was " + oldest.Round(time.Second).String() + " ago"}
+	}
+	return Check{true, "last heartbeat ACK " + oldest.Round(time.Second).String() + " ago"}
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,301 @@
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metric is anything that can write itself in the Prometheus text format
+type metric interface {
+	write(w io.Writer)
+}
+
+// registry holds every metric in the order it was registered so the output is stable
+var (
+	registry     []metric
+	registryLock sync.Mutex
+)
+
+// register adds a metric to the registry
+func register(m metric) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registry = append(registry, m)
+}
+
+// WriteMetrics writes every registered metric in the Prometheus text format
+func WriteMetrics(w io.Writer) {
+	registryLock.Lock()
+	metrics := append([]metric(nil), registry...)
+	registryLock.Unlock()
+
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// CounterVec is a counter split up by label values
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	lock   sync.Mutex
+	values map[string]float64
+}
+
+// NewCounterVec registers a new counter with the given label names
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{name: name, help: help, labels: labels, values: make(map[string]float64)}
+	register(c)
+	return c
+}
+
+// Inc adds one to the counter for the given label values
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds n to the counter for the given label values
+func (c *CounterVec) Add(n float64, values ...string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.values[seriesKey(values)] += n
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	writeHeader(w, c.name, c.help, "counter")
+	if len(c.labels) == 0 && len(c.values) == 0 {
+		// plain counters should always show up, even before anything happened
+		fmt.Fprintf(w, "%s 0\n", c.name)
+		return
+	}
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, labelString(c.labels, splitKey(key), "", ""), formatFloat(c.values[key]))
+	}
+}
+
+// HistogramVec is a histogram split up by label values
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	lock   sync.Mutex
+	series map[string]*histogram
+}
+
+// histogram is a single series of a HistogramVec
+type histogram struct {
+	counts []uint64 // one per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// DefaultBuckets are latency buckets in seconds, from 5ms up to 10s
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// NewHistogramVec registers a new histogram with the given buckets and label names
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{name: name, help: help, labels: labels, buckets: buckets, series: make(map[string]*histogram)}
+	register(h)
+	return h
+}
+
+// Observe records a value for the given label values
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	key := seriesKey(values)
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.counts[i]++
+			break
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	writeHeader(w, h.name, h.help, "histogram")
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := h.series[key]
+		values := splitKey(key)
+
+		// prometheus buckets are cumulative so we add them up as we go
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelString(h.labels, values, "le", formatFloat(upper)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelString(h.labels, values, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labelString(h.labels, values, "", ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labelString(h.labels, values, "", ""), s.count)
+	}
+}
+
+// GaugeFunc is a gauge whose value is read when metrics are scraped
+type GaugeFunc struct {
+	name string
+	help string
+	fn   func() float64
+}
+
+// NewGaugeFunc registers a gauge that calls fn every scrape
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{name: name, help: help, fn: fn}
+	register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(w io.Writer) {
+	writeHeader(w, g.name, g.help, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.fn()))
+}
+
+// GaugeVecFunc is a gauge split up by label values, every series is read when metrics are scraped
+type GaugeVecFunc struct {
+	name   string
+	help   string
+	labels []string
+	fn     func(set func(value float64, labels ...string))
+}
+
+// NewGaugeVecFunc registers a labeled gauge, fn calls set once for every series it wants to report
+func NewGaugeVecFunc(name, help string, labels []string, fn func(set func(value float64, labels ...string))) *GaugeVecFunc {
+	g := &GaugeVecFunc{name: name, help: help, labels: labels, fn: fn}
+	register(g)
+	return g
+}
+
+func (g *GaugeVecFunc) write(w io.Writer) {
+	writeHeader(w, g.name, g.help, "gauge")
+	g.fn(func(value float64, labels ...string) {
+		fmt.Fprintf(w, "%s%s %s\n", g.name, labelString(g.labels, labels, "", ""), formatFloat(value))
+	})
+}
+
+// runtimeMetrics writes the Go runtime stats, we read MemStats once per scrape
+type runtimeMetrics struct{}
+
+func (runtimeMetrics) write(w io.Writer) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	gauges := []struct {
+		name, help string
+		value      float64
+	}{
+		{"go_goroutines", "Number of goroutines that currently exist.", float64(runtime.NumGoroutine())},
+		{"go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", float64(mem.Alloc)},
+		{"go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", float64(mem.HeapInuse)},
+		{"go_memstats_heap_objects", "Number of allocated objects.", float64(mem.HeapObjects)},
+		{"go_memstats_sys_bytes", "Number of bytes obtained from the system.", float64(mem.Sys)},
+		{"go_memstats_last_gc_time_seconds", "Number of seconds since 1970 of last garbage collection.", float64(mem.LastGC) / 1e9},
+		{"process_start_time_seconds", "Start time of the process since unix epoch in seconds.", float64(startTime.Unix())},
+	}
+	for _, g := range gauges {
+		writeHeader(w, g.name, g.help, "gauge")
+		fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.value))
+	}
+
+	writeHeader(w, "go_gc_cycles_total", "Number of completed GC cycles.", "counter")
+	fmt.Fprintf(w, "go_gc_cycles_total %d\n", mem.NumGC)
+	writeHeader(w, "go_gc_pause_seconds_total", "Total time spent in GC stop-the-world pauses.", "counter")
+	fmt.Fprintf(w, "go_gc_pause_seconds_total %s\n", formatFloat(float64(mem.PauseTotalNs)/1e9))
+}
+
+// startTime is when the process started, close enough since this package loads at startup
+var startTime = time.Now()
+
+// writeHeader writes the HELP and TYPE lines for a metric
+func writeHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// labelString formats label pairs like {command="ping",type="prefix"}, extraName/extraValue is used for "le"
+func labelString(names, values []string, extraName, extraValue string) string {
+	pairs := make([]string, 0, len(names)+1)
+	for i, name := range names {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", name, escapeLabel(value)))
+	}
+	if extraName != "" {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", extraName, extraValue))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// escapeLabel escapes a label value the way the text format expects
+func escapeLabel(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return strings.ReplaceAll(s, `"`, `\"`)
+}
+
+// seriesKey joins label values into a map key, \xff cant show up in a label so its a safe separator
+func seriesKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// splitKey turns a series key back into label values
+func splitKey(key string) []string {
+	return strings.Split(key, "\xff")
+}
+
+// sortedKeys returns the keys of a map in order so scrapes dont shuffle around
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatFloat formats a value the way prometheus likes it
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,51 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"template/logging"
+	"time"
+)
+
+// mux is shared by everything the monitor serves
+var mux = http.NewServeMux()
+
+// server is the running HTTP server, nil when the listener is disabled
+var server *http.Server
+
+func init() {
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WriteMetrics(w)
+	})
+}
+
+// Start starts the HTTP listener on the given address, an empty address leaves it disabled
+func Start(address string) {
+	if address == "" {
+		return
+	}
+
+	server = &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			// the bot still works without metrics so we just log it
+			logging.Error("Monitor listener failed: %v", err)
+		}
+	}()
+	logging.Info("Monitor listening on %s", address)
+}
+
+// Stop shuts the HTTP listener down
+func Stop(ctx context.Context) error {
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
+}
@@ -0,0 +1,100 @@
+package monitor
+
+import (
+	"bufio"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// System is a snapshot of the process and the host it runs on
+type System struct {
+	HeapAlloc  uint64 // bytes allocated on the heap and still in use
+	HeapSys    uint64 // bytes of heap obtained from the OS
+	Sys        uint64 // total bytes obtained from the OS
+	RSS        uint64 // resident set size, 0 when we cant read it
+	Goroutines int
+	NumGC      uint32
+	LastGC     time.Time
+	PauseTotal time.Duration
+	GoVersion  string
+	OS         string
+	Arch       string
+	CPUs       int
+	Load       [3]float64 // 1, 5 and 15 minute load averages
+	HasLoad    bool       // false when the load average isnt available (anything but linux)
+}
+
+// ReadSystem reads the current runtime and host stats
+// RSS and the load average come from /proc so they are only filled in on linux
+func ReadSystem() System {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	sys := System{
+		HeapAlloc:  mem.HeapAlloc,
+		HeapSys:    mem.HeapSys,
+		Sys:        mem.Sys,
+		Goroutines: runtime.NumGoroutine(),
+		NumGC:      mem.NumGC,
+		PauseTotal: time.Duration(mem.PauseTotalNs),
+		GoVersion:  runtime.Version(),
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		CPUs:       runtime.NumCPU(),
+	}
+	if mem.LastGC > 0 {
+		sys.LastGC = time.Unix(0, int64(mem.LastGC))
+	}
+
+	sys.RSS = readRSS()
+	sys.Load, sys.HasLoad = readLoad()
+	return sys
+}
+
+// readRSS reads VmRSS from /proc/self/status
+func readRSS() uint64 {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// the line looks like "VmRSS:     12345 kB"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "VmRSS:" {
+			kb, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kb * 1024
+		}
+	}
+	return 0
+}
+
+// readLoad reads the load averages from /proc/loadavg
+func readLoad() ([3]float64, bool) {
+	var load [3]float64
+
+	b, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return load, false
+	}
+
+	// the file looks like "0.52 0.58 0.59 1/389 12345"
+	fields := strings.Fields(string(b))
+	if len(fields) < 3 {
+		return load, false
+	}
+	for i := range load {
+		if load[i], err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return load, false
+		}
+	}
+	return load, true
+}
@@ -0,0 +1,299 @@
+package presence
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"template/config"
+	"template/logging"
+	"template/shards"
+	"template/util/timeutil"
+	"text/template"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// DefaultInterval is how often we rotate activities when the config doesnt say
+const DefaultInterval = time.Minute
+
+// Activity is a single entry in the rotation, Text can use template variables like {{.Guilds}}
+type Activity struct {
+	Type string // playing, watching, listening, competing or custom
+	Text string
+}
+
+// Data is what activity templates can use
+type Data struct {
+	Guilds int    // guilds across every shard
+	Shards int    // how many shards we run
+	Uptime string // how long we have been running (e.g. 2d 3h 12m)
+	Prefix string // the command prefix
+	Brand  config.BrandConfig
+}
+
+// activityTypes maps the names used in the config (and the admin command) to discord activity types
+var activityTypes = map[string]discordgo.ActivityType{
+	"playing":   discordgo.ActivityTypeGame,
+	"watching":  discordgo.ActivityTypeWatching,
+	"listening": discordgo.ActivityTypeListening,
+	"competing": discordgo.ActivityTypeCompeting,
+	"custom":    discordgo.ActivityTypeCustom,
+}
+
+// statuses are the online statuses we accept
+var statuses = map[string]bool{"online": true, "idle": true, "dnd": true, "invisible": true}
+
+var (
+	lock    sync.Mutex
+	index   int       // which activity in the rotation is showing
+	pinned  *Activity // set at runtime with the presence command, stops the rotation until it's reset
+	status  string    // set at runtime, empty means the config status
+	ticker  *time.Ticker
+	stop    chan struct{}
+	started = time.Now()
+)
+
+// ActivityTypes returns the activity type names we understand
+func ActivityTypes() []string {
+	return []string{"playing", "watching", "listening", "competing", "custom"}
+}
+
+// Check returns a line for every status or activity in the presence config we dont understand, or that fails to parse
+func Check(c config.PresenceConfig) []string {
+	var problems []string
+	if c.Status != "" && !statuses[strings.ToLower(c.Status)] {
+		problems = append(problems, fmt.Sprintf("presence.status %q should be online, idle, dnd or invisible", c.Status))
+	}
+	for i, a := range c.Activities {
+		if _, ok := activityTypes[strings.ToLower(a.Type)]; !ok && a.Type != "" {
+			problems = append(problems, fmt.Sprintf("presence.activities[%d].type %q should be one of %s", i, a.Type, strings.Join(ActivityTypes(), ", ")))
+		}
+		if _, err := template.New("activity").Parse(a.Text); err != nil {
+			problems = append(problems, fmt.Sprintf("presence.activities[%d].text: %v", i, err))
+		}
+	}
+	return problems
+}
+
+// Start starts rotating through the configured activities, does nothing when presence is disabled
+func Start() {
+	c := config.Config.Presence
+	if !c.Enabled {
+		return
+	}
+
+	interval := time.Duration(c.Interval) * time.Second
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if ticker != nil {
+		return
+	}
+	ticker = time.NewTicker(interval)
+	stop = make(chan struct{})
+	go rotate(ticker, stop)
+}
+
+// Stop stops the rotation
+func Stop() {
+	lock.Lock()
+	defer lock.Unlock()
+	if ticker == nil {
+		return
+	}
+	ticker.Stop()
+	close(stop)
+	ticker = nil
+}
+
+// Restore sets the current presence on a single session, the ready handler calls this because
+// discord forgets the presence whenever a shard has to start a fresh session
+func Restore(s *discordgo.Session) {
+	if !config.Config.Presence.Enabled {
+		return
+	}
+
+	lock.Lock()
+	update, err := current()
+	lock.Unlock()
+	if err != nil {
+		logging.Warn("Failed to build presence: %v", err)
+		return
+	}
+
+	if err = s.UpdateStatusComplex(update); err != nil {
+		logging.Warn("Failed to set presence on shard %d: %v", s.ShardID, err)
+	}
+}
+
+// Set pins an activity (and optionally a status) until Reset is called, it shows up on every shard right away
+func Set(activityType, text, newStatus string) error {
+	if _, ok := activityTypes[strings.ToLower(activityType)]; !ok && activityType != "" {
+		return fmt.Errorf("unknown activity type %q, use one of %s", activityType, strings.Join(ActivityTypes(), ", "))
+	}
+	if newStatus != "" && !statuses[strings.ToLower(newStatus)] {
+		return fmt.Errorf("unknown status %q, use online, idle, dnd or invisible", newStatus)
+	}
+
+	// we check the template now so a typo gets reported to whoever set it instead of showing up in the logs later
+	if text != "" {
+		if _, err := template.New("presence").Parse(text); err != nil {
+			return err
+		}
+	}
+
+	lock.Lock()
+	if activityType != "" {
+		pinned = &Activity{Type: strings.ToLower(activityType), Text: text}
+	}
+	if newStatus != "" {
+		status = strings.ToLower(newStatus)
+	}
+	lock.Unlock()
+
+	return apply()
+}
+
+// Reset drops anything set at runtime and goes back to the configured rotation
+func Reset() error {
+	lock.Lock()
+	pinned = nil
+	status = ""
+	lock.Unlock()
+	return apply()
+}
+
+// Current describes what the presence is set to right now
+func Current() (activity Activity, currentStatus string, isPinned bool) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	currentStatus = currentStatusName()
+	if pinned != nil {
+		return *pinned, currentStatus, true
+	}
+	if activities := config.Config.Presence.Activities; len(activities) > 0 {
+		return toActivity(activities[index%len(activities)]), currentStatus, false
+	}
+	return Activity{}, currentStatus, false
+}
+
+// rotate moves to the next activity every tick
+func rotate(t *time.Ticker, stop chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			lock.Lock()
+			if pinned == nil {
+				index++
+			}
+			lock.Unlock()
+
+			if err := apply(); err != nil {
+				logging.Warn("Failed to rotate presence: %v", err)
+			}
+		}
+	}
+}
+
+// apply sends the current presence to every shard
+func apply() error {
+	lock.Lock()
+	update, err := current()
+	lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range shards.Sessions() {
+		if err := s.UpdateStatusComplex(update); err != nil {
+			// a shard that is reconnecting gets it again from Restore once its ready
+			logging.Warn("Failed to set presence on shard %d: %v", s.ShardID, err)
+		}
+	}
+	return nil
+}
+
+// current builds the presence update for whatever should be showing (caller must hold the lock)
+func current() (discordgo.UpdateStatusData, error) {
+	update := discordgo.UpdateStatusData{Status: currentStatusName()}
+
+	activity := pinned
+	if activity == nil {
+		if activities := config.Config.Presence.Activities; len(activities) > 0 {
+			a := toActivity(activities[index%len(activities)])
+			activity = &a
+		}
+	}
+	if activity == nil || activity.Text == "" {
+		return update, nil
+	}
+
+	text, err := render(activity.Text)
+	if err != nil {
+		return update, err
+	}
+
+	kind := activityTypes[activity.Type]
+	a := &discordgo.Activity{Name: text, Type: kind}
+	if kind == discordgo.ActivityTypeCustom {
+		// custom statuses show the state, the name just has to be set to something
+		a.Name = "Custom Status"
+		a.State = text
+	}
+	update.Activities = []*discordgo.Activity{a}
+	return update, nil
+}
+
+// currentStatusName returns the online status to use (caller must hold the lock)
+func currentStatusName() string {
+	if status != "" {
+		return status
+	}
+	if s := strings.ToLower(config.Config.Presence.Status); statuses[s] {
+		return s
+	}
+	return "online"
+}
+
+// render fills in the template variables of an activity
+func render(text string) (string, error) {
+	t, err := template.New("presence").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	data := Data{
+		Shards: shards.Count(),
+		// seconds would just make the status change every rotation so we stop at minutes
+		Uptime: timeutil.Humanize(time.Since(started), timeutil.Options{Short: true, Smallest: timeutil.Minute}),
+		Prefix: config.Config.Prefix,
+		Brand:  config.Config.Brand,
+	}
+	for _, st := range shards.Statuses() {
+		data.Guilds += st.Guilds
+	}
+
+	var b bytes.Buffer
+	if err = t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	// discord cuts activity names off at 128 characters, we cut on runes so we dont split an emoji in half
+	out := []rune(b.String())
+	if len(out) > 128 {
+		out = out[:128]
+	}
+	return string(out), nil
+}
+
+// toActivity turns a configured activity into ours
+func toActivity(a config.PresenceActivity) Activity {
+	return Activity{Type: strings.ToLower(a.Type), Text: a.Text}
+}
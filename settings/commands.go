@@ -0,0 +1,163 @@
+package settings
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Where a command can be turned off
+const (
+	LevelGlobal  = "global"
+	LevelGuild   = "guild"
+	LevelChannel = "channel"
+)
+
+// commandSettings is what we save for a single command
+type commandSettings struct {
+	Disabled Disabled `json:"disabled"`
+}
+
+// empty reports whether there is nothing saved for the command anymore
+func (c *commandSettings) empty() bool {
+	return c.Disabled.empty()
+}
+
+// Disabled lists everywhere a command is turned off
+type Disabled struct {
+	Global   bool     `json:"global,omitempty"`
+	Guilds   []string `json:"guilds,omitempty"`
+	Channels []string `json:"channels,omitempty"`
+}
+
+// empty reports whether the command isnt turned off anywhere
+func (d Disabled) empty() bool {
+	return !d.Global && len(d.Guilds) == 0 && len(d.Channels) == 0
+}
+
+// SetDisabled turns a command off (or back on) globally, in a guild or in a channel and saves it
+// id is the guild or channel ID and is ignored for LevelGlobal, it returns false when nothing changed
+func SetDisabled(command, level, id string, disabled bool) (bool, error) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if store.Commands == nil {
+		store.Commands = make(map[string]*commandSettings)
+	}
+	// we change a copy so nothing is live until it is saved
+	c := store.Commands[command].clone()
+
+	var changed bool
+	switch level {
+	case LevelGlobal:
+		changed = c.Disabled.Global != disabled
+		c.Disabled.Global = disabled
+	case LevelGuild:
+		c.Disabled.Guilds, changed = toggle(c.Disabled.Guilds, id, disabled)
+	case LevelChannel:
+		c.Disabled.Channels, changed = toggle(c.Disabled.Channels, id, disabled)
+	default:
+		return false, fmt.Errorf("unknown level %q", level)
+	}
+	if !changed {
+		return false, nil
+	}
+
+	return true, put(command, c)
+}
+
+// clone returns a copy of a command's settings that can be changed without touching the live ones, nil gives empty settings
+func (c *commandSettings) clone() *commandSettings {
+	if c == nil {
+		return &commandSettings{}
+	}
+	return &commandSettings{Disabled: Disabled{
+		Global:   c.Disabled.Global,
+		Guilds:   slices.Clone(c.Disabled.Guilds),
+		Channels: slices.Clone(c.Disabled.Channels),
+	}}
+}
+
+// put swaps in a command's new settings and saves them (caller must hold the lock)
+// when saving fails the old settings go back so what is live always matches the file
+// we dont keep empty entries around so the file only lists commands that actually have something set
+func put(command string, c *commandSettings) error {
+	old, existed := store.Commands[command]
+	if c.empty() {
+		delete(store.Commands, command)
+	} else {
+		store.Commands[command] = c
+	}
+
+	if err := save(); err != nil {
+		if existed {
+			store.Commands[command] = old
+		} else {
+			delete(store.Commands, command)
+		}
+		return err
+	}
+	return nil
+}
+
+// toggle adds or removes an ID from a list and reports whether the list changed
+func toggle(list []string, id string, add bool) ([]string, bool) {
+	i := slices.Index(list, id)
+	switch {
+	case add && i == -1:
+		return append(list, id), true
+	case !add && i != -1:
+		return slices.Delete(list, i, i+1), true
+	}
+	return list, false
+}
+
+// DisabledAt reports whether a command is turned off for a channel in a guild, and at which level
+// channelIDs is the channel plus the parent for threads (see util.ChannelIDs) so disabling a channel covers its threads
+// the widest level wins so "disabled everywhere" is what people see even if the channel is off too
+func DisabledAt(command, guildID string, channelIDs []string) (bool, string) {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	c, ok := store.Commands[command]
+	if !ok {
+		return false, ""
+	}
+	switch {
+	case c.Disabled.Global:
+		return true, LevelGlobal
+	case guildID != "" && slices.Contains(c.Disabled.Guilds, guildID):
+		return true, LevelGuild
+	case slices.ContainsFunc(channelIDs, func(id string) bool { return slices.Contains(c.Disabled.Channels, id) }):
+		return true, LevelChannel
+	}
+	return false, ""
+}
+
+// DisabledMessage is what we tell users when they try a command that is turned off
+func DisabledMessage(level string) string {
+	switch level {
+	case LevelGuild:
+		return "This command is disabled in this server"
+	case LevelChannel:
+		return "This command is disabled in this channel"
+	}
+	return "This command is currently disabled"
+}
+
+// DisabledCommands returns every command that is turned off somewhere keyed by name
+func DisabledCommands() map[string]Disabled {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	out := make(map[string]Disabled, len(store.Commands))
+	for name, c := range store.Commands {
+		if !c.Disabled.empty() {
+			out[name] = Disabled{
+				Global:   c.Disabled.Global,
+				Guilds:   slices.Clone(c.Disabled.Guilds),
+				Channels: slices.Clone(c.Disabled.Channels),
+			}
+		}
+	}
+	return out
+}
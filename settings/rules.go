@@ -0,0 +1,208 @@
+package settings
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"template/config"
+)
+
+// Rule modes and kinds, a rule is "allow/deny a channel/category/role"
+const (
+	RuleAllow = "allow"
+	RuleDeny  = "deny"
+
+	RuleChannel  = "channel"
+	RuleCategory = "category"
+	RuleRole     = "role"
+)
+
+// Access is where and by whom a command is being used, the dispatcher fills it in before checking the rules
+type Access struct {
+	// GuildID is the guild the command was used in, empty in DMs. only that guild's runtime rules are checked
+	GuildID string
+	// ChannelIDs is the channel the command was used in, plus the channel a thread belongs to so rules for a channel cover its threads
+	ChannelIDs []string
+	// CategoryID is the category the channel is in, empty when it isnt in one
+	CategoryID string
+	// Roles are the roles of the member using the command, empty in DMs
+	Roles []string
+}
+
+// ruleList returns a pointer to the list a mode and kind refer to
+func ruleList(r *config.CommandRule, mode, kind string) (*[]string, error) {
+	switch mode + " " + kind {
+	case "allow channel":
+		return &r.AllowChannels, nil
+	case "deny channel":
+		return &r.DenyChannels, nil
+	case "allow category":
+		return &r.AllowCategories, nil
+	case "deny category":
+		return &r.DenyCategories, nil
+	case "allow role":
+		return &r.AllowRoles, nil
+	case "deny role":
+		return &r.DenyRoles, nil
+	}
+	return nil, fmt.Errorf("unknown rule %s %s", mode, kind)
+}
+
+// cloneRule returns a copy of a rule that can be changed without touching the original, nil gives an empty rule
+func cloneRule(r *config.CommandRule) *config.CommandRule {
+	if r == nil {
+		return &config.CommandRule{}
+	}
+	return &config.CommandRule{
+		AllowChannels:   slices.Clone(r.AllowChannels),
+		DenyChannels:    slices.Clone(r.DenyChannels),
+		AllowCategories: slices.Clone(r.AllowCategories),
+		DenyCategories:  slices.Clone(r.DenyCategories),
+		AllowRoles:      slices.Clone(r.AllowRoles),
+		DenyRoles:       slices.Clone(r.DenyRoles),
+	}
+}
+
+// ruleEmpty reports whether a rule has nothing in it
+func ruleEmpty(r config.CommandRule) bool {
+	return len(r.AllowChannels) == 0 && len(r.DenyChannels) == 0 &&
+		len(r.AllowCategories) == 0 && len(r.DenyCategories) == 0 &&
+		len(r.AllowRoles) == 0 && len(r.DenyRoles) == 0
+}
+
+// SetRule adds an ID to (or with add false removes it from) one of a command's runtime allow/deny lists in a guild and saves it
+// it returns false when nothing changed, rules from the config cant be removed this way
+func SetRule(guildID, command, mode, kind, id string, add bool) (bool, error) {
+	if guildID == "" {
+		return false, fmt.Errorf("rules can only be changed in a server")
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if store.Guilds == nil {
+		store.Guilds = make(map[string]*guildSettings)
+	}
+	// we change a copy so nothing is live until it is saved
+	g := store.Guilds[guildID].clone()
+	if g.Rules == nil {
+		g.Rules = make(map[string]*config.CommandRule)
+	}
+	r := cloneRule(g.Rules[command])
+
+	list, err := ruleList(r, mode, kind)
+	if err != nil {
+		return false, err
+	}
+	var changed bool
+	if *list, changed = toggle(*list, id, add); !changed {
+		return false, nil
+	}
+
+	if ruleEmpty(*r) {
+		delete(g.Rules, command)
+	} else {
+		g.Rules[command] = r
+	}
+	return true, putGuild(guildID, g)
+}
+
+// ClearRules removes every runtime rule a guild added for a command and returns false if it didnt have any
+func ClearRules(guildID, command string) (bool, error) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	saved, ok := store.Guilds[guildID]
+	if !ok || saved.Rules[command] == nil {
+		return false, nil
+	}
+	g := saved.clone()
+	delete(g.Rules, command)
+	return true, putGuild(guildID, g)
+}
+
+// Rules returns the rules for a command from the config and the ones a guild added at runtime
+// rules from the config apply everywhere, the runtime ones only in the guild that added them
+func Rules(guildID, command string) (fromConfig, runtime config.CommandRule) {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	fromConfig = config.Config.CommandRules[command]
+	if g, ok := store.Guilds[guildID]; ok && guildID != "" && g.Rules[command] != nil {
+		runtime = *g.Rules[command]
+	}
+	return fromConfig, runtime
+}
+
+// effectiveRule merges the config rules with the ones the guild added
+func effectiveRule(guildID, command string) config.CommandRule {
+	a, b := Rules(guildID, command)
+	return config.CommandRule{
+		AllowChannels:   append(slices.Clone(a.AllowChannels), b.AllowChannels...),
+		DenyChannels:    append(slices.Clone(a.DenyChannels), b.DenyChannels...),
+		AllowCategories: append(slices.Clone(a.AllowCategories), b.AllowCategories...),
+		DenyCategories:  append(slices.Clone(a.DenyCategories), b.DenyCategories...),
+		AllowRoles:      append(slices.Clone(a.AllowRoles), b.AllowRoles...),
+		DenyRoles:       append(slices.Clone(a.DenyRoles), b.DenyRoles...),
+	}
+}
+
+// CheckAccess checks a command's allow/deny lists, when it isnt allowed the reason is returned ready to show the user
+// deny lists win over allow lists, and the channel has to pass before we look at roles
+func CheckAccess(command string, a Access) (bool, string) {
+	r := effectiveRule(a.GuildID, command)
+	if ruleEmpty(r) {
+		return true, ""
+	}
+
+	for _, id := range a.ChannelIDs {
+		if slices.Contains(r.DenyChannels, id) {
+			return false, "This command cant be used in this channel"
+		}
+	}
+	if a.CategoryID != "" && slices.Contains(r.DenyCategories, a.CategoryID) {
+		return false, "This command cant be used in this category"
+	}
+
+	// an allow list for channels or categories means the command only works there, either one matching is enough
+	if len(r.AllowChannels) > 0 || len(r.AllowCategories) > 0 {
+		allowed := a.CategoryID != "" && slices.Contains(r.AllowCategories, a.CategoryID)
+		for _, id := range a.ChannelIDs {
+			allowed = allowed || slices.Contains(r.AllowChannels, id)
+		}
+		if !allowed {
+			return false, "This command can only be used in " + mentionList(r.AllowChannels, r.AllowCategories)
+		}
+	}
+
+	for _, role := range a.Roles {
+		if slices.Contains(r.DenyRoles, role) {
+			return false, fmt.Sprintf("Members with <@&%s> cant use this command", role)
+		}
+	}
+	if len(r.AllowRoles) > 0 && !slices.ContainsFunc(a.Roles, func(role string) bool { return slices.Contains(r.AllowRoles, role) }) {
+		return false, "You need one of these roles to use this command: " + roleList(r.AllowRoles)
+	}
+	return true, ""
+}
+
+// mentionList turns channel and category IDs into mentions, discord renders category mentions as their name too
+func mentionList(channels, categories []string) string {
+	var mentions []string
+	for _, id := range channels {
+		mentions = append(mentions, "<#"+id+">")
+	}
+	for _, id := range categories {
+		mentions = append(mentions, "<#"+id+"> (category)")
+	}
+	return strings.Join(mentions, ", ")
+}
+
+// roleList turns role IDs into mentions
+func roleList(roles []string) string {
+	mentions := make([]string, len(roles))
+	for i, id := range roles {
+		mentions[i] = "<@&" + id + ">"
+	}
+	return strings.Join(mentions, ", ")
+}
@@ -0,0 +1,111 @@
+package settings
+
+import (
+	"fmt"
+	"strings"
+	"template/config"
+	"unicode/utf8"
+)
+
+// MaxPrefixLength is how long a guild prefix can be
+const MaxPrefixLength = 10
+
+// Guild is everything that can be configured for a single guild, anything a guild hasnt set falls back to the config
+type Guild struct {
+	ID     string // empty for DMs
+	Prefix string // the main prefix, what help and the bare mention reply show
+	// Prefixes is every prefix that works here, the main one first (mentions are handled separately)
+	Prefixes []string
+	// CustomPrefix is true when the guild set its own prefix instead of using the one from the config
+	CustomPrefix bool
+}
+
+// guildSettings is what we save for a single guild
+type guildSettings struct {
+	Prefix string `json:"prefix,omitempty"`
+	// Rules are the allow/deny lists added with .command in this guild keyed by command name, on top of command_rules from the config
+	Rules map[string]*config.CommandRule `json:"rules,omitempty"`
+}
+
+// empty reports whether there is nothing saved for the guild anymore
+func (g *guildSettings) empty() bool {
+	return g.Prefix == "" && len(g.Rules) == 0
+}
+
+// Resolve returns the settings that apply in a guild, pass an empty ID for DMs
+func Resolve(guildID string) Guild {
+	g := Guild{
+		ID:     guildID,
+		Prefix: config.Config.Prefix,
+	}
+
+	lock.RLock()
+	if saved, ok := store.Guilds[guildID]; ok && saved.Prefix != "" && guildID != "" {
+		// a guild prefix replaces the main one, the extra prefixes from the config keep working next to it
+		g.Prefix = saved.Prefix
+		g.CustomPrefix = true
+	}
+	lock.RUnlock()
+
+	g.Prefixes = append([]string{g.Prefix}, config.Config.Prefixes...)
+	return g
+}
+
+// SetPrefix changes the main prefix of a guild and saves it, an empty prefix goes back to the one in the config
+func SetPrefix(guildID, prefix string) error {
+	if guildID == "" {
+		return fmt.Errorf("the prefix can only be changed in a server")
+	}
+	if strings.TrimSpace(prefix) != prefix || strings.ContainsAny(prefix, " \n\t") {
+		return fmt.Errorf("prefixes cant contain spaces")
+	}
+	if utf8.RuneCountInString(prefix) > MaxPrefixLength {
+		return fmt.Errorf("prefixes can be at most %d characters", MaxPrefixLength)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if store.Guilds == nil {
+		store.Guilds = make(map[string]*guildSettings)
+	}
+	g := store.Guilds[guildID].clone()
+	g.Prefix = prefix
+	return putGuild(guildID, g)
+}
+
+// clone returns a copy of a guild's settings that can be changed without touching the live ones, nil gives empty settings
+func (g *guildSettings) clone() *guildSettings {
+	if g == nil {
+		return &guildSettings{}
+	}
+	c := &guildSettings{Prefix: g.Prefix}
+	if g.Rules != nil {
+		c.Rules = make(map[string]*config.CommandRule, len(g.Rules))
+		for name, r := range g.Rules {
+			c.Rules[name] = cloneRule(r)
+		}
+	}
+	return c
+}
+
+// putGuild swaps in a guild's new settings and saves them (caller must hold the lock)
+// like put the old settings go back when saving fails, and nothing set means nothing saved
+func putGuild(guildID string, g *guildSettings) error {
+	old, existed := store.Guilds[guildID]
+	if g.empty() {
+		delete(store.Guilds, guildID)
+	} else {
+		store.Guilds[guildID] = g
+	}
+
+	if err := save(); err != nil {
+		if existed {
+			store.Guilds[guildID] = old
+		} else {
+			delete(store.Guilds, guildID)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package settings
+
+import "slices"
+
+// SlashTargets returns the guilds (and "" for global) our slash commands were last registered in
+func SlashTargets() []string {
+	lock.RLock()
+	defer lock.RUnlock()
+	return slices.Clone(store.SlashTargets)
+}
+
+// SetSlashTargets remembers where our slash commands are registered, so a guild that gets dropped from the config
+// is still cleaned up on the next start instead of keeping its old copies forever
+func SetSlashTargets(targets []string) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if slices.Equal(store.SlashTargets, targets) {
+		return nil
+	}
+	old := store.SlashTargets
+	store.SlashTargets = slices.Clone(targets)
+	if err := save(); err != nil {
+		store.SlashTargets = old
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"template/config"
+)
+
+// DefaultFile is where runtime settings are saved when the config doesnt say
+const DefaultFile = "./data/settings.json"
+
+// data is everything admins can change at runtime, it is saved as JSON after every change
+type data struct {
+	// Commands holds per command settings keyed by command name
+	Commands map[string]*commandSettings `json:"commands,omitempty"`
+	// Guilds holds per guild settings keyed by guild ID
+	Guilds map[string]*guildSettings `json:"guilds,omitempty"`
+	// SlashTargets are the guilds ("" for global) our slash commands were last registered in
+	SlashTargets []string `json:"slash_targets,omitempty"`
+}
+
+var (
+	lock  sync.RWMutex
+	store = data{}
+)
+
+// path returns the settings file from the config
+func path() string {
+	if config.Config.SettingsFile != "" {
+		return config.Config.SettingsFile
+	}
+	return DefaultFile
+}
+
+// Open loads the saved settings, a missing file just means nothing was changed yet
+func Open() error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	f, err := os.ReadFile(path())
+	if errors.Is(err, os.ErrNotExist) {
+		store = data{}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var loaded data
+	if err = json.Unmarshal(f, &loaded); err != nil {
+		return err
+	}
+	store = loaded
+	return nil
+}
+
+// save writes the settings to disk (caller must hold the lock)
+// we write to a temp file and rename it over the old one so a crash mid write cant leave half a file behind
+func save() error {
+	b, err := json.MarshalIndent(store, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path()), 0o755); err != nil {
+		return err
+	}
+	tmp := path() + ".tmp"
+	if err = os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path())
+}
@@ -0,0 +1,244 @@
+package shards
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"template/logging"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// identifyInterval is how long Discord wants between identifies in the same rate limit bucket
+const identifyInterval = 5 * time.Second
+
+// Status is a snapshot of a single shard
+type Status struct {
+	ID               int
+	Ready            bool          // we got READY and havent disconnected since
+	ReadyAt          time.Time     // when the last READY came in
+	Latency          time.Duration // heartbeat latency
+	LastHeartbeatAck time.Time
+	Guilds           int
+}
+
+// shard is one gateway connection
+type shard struct {
+	session *discordgo.Session
+
+	lock    sync.RWMutex
+	ready   bool
+	readyAt time.Time
+}
+
+var (
+	lock           sync.RWMutex
+	shards         []*shard
+	maxConcurrency = 1
+)
+
+// Setup creates a session for every shard, a count of 0 asks Discord how many shards we should run
+// nothing connects until Open is called so handlers can be added first
+func Setup(token string, count int, intents discordgo.Intent) error {
+	primary, err := discordgo.New("Bot " + token)
+	if err != nil {
+		return err
+	}
+
+	// gateway/bot tells us the recommended shard count and how many shards can identify at once
+	gateway, err := primary.GatewayBot()
+	if err != nil {
+		if count <= 0 {
+			return fmt.Errorf("fetching the recommended shard count: %w", err)
+		}
+		// we were told how many shards to run so this isnt fatal, we just play it safe with the identify rate
+		logging.Warn("Failed to fetch gateway info, starting shards one at a time: %v", err)
+	}
+
+	concurrency := 1
+	if gateway != nil {
+		if count <= 0 {
+			count = gateway.Shards
+		}
+		if gateway.SessionStartLimit.MaxConcurrency > 0 {
+			concurrency = gateway.SessionStartLimit.MaxConcurrency
+		}
+		if gateway.SessionStartLimit.Remaining < count {
+			reset := time.Duration(gateway.SessionStartLimit.ResetAfter) * time.Millisecond
+			logging.Warn("Only %d session starts left (resets in %s), starting %d shards might fail", gateway.SessionStartLimit.Remaining, reset.Round(time.Second), count)
+		}
+	}
+	if count < 1 {
+		count = 1
+	}
+
+	created := make([]*shard, 0, count)
+	for id := 0; id < count; id++ {
+		s := primary
+		if id > 0 {
+			if s, err = discordgo.New("Bot " + token); err != nil {
+				return err
+			}
+			// rate limits are per bot not per connection so every shard shares the same REST buckets
+			s.Ratelimiter = primary.Ratelimiter
+		}
+		s.ShardID = id
+		s.ShardCount = count
+		s.Identify.Intents = intents
+
+		sh := &shard{session: s}
+		s.AddHandler(sh.onReady)
+		s.AddHandler(sh.onResumed)
+		s.AddHandler(sh.onDisconnect)
+		created = append(created, sh)
+	}
+
+	lock.Lock()
+	shards = created
+	maxConcurrency = concurrency
+	lock.Unlock()
+	return nil
+}
+
+// AddHandler adds an event handler to every shard, handlers get the session of the shard the event came from
+func AddHandler(handler interface{}) {
+	for _, s := range Sessions() {
+		s.AddHandler(handler)
+	}
+}
+
+// Open connects every shard, shards in the same batch of max_concurrency identify together
+// and each batch waits for the identify rate limit before the next one starts
+func Open() error {
+	lock.RLock()
+	all := append([]*shard(nil), shards...)
+	batch := maxConcurrency
+	lock.RUnlock()
+
+	if len(all) > 1 {
+		logging.Info("Starting %d shards (%d at a time)", len(all), batch)
+	}
+
+	for start := 0; start < len(all); start += batch {
+		if start > 0 {
+			time.Sleep(identifyInterval)
+		}
+		end := start + batch
+		if end > len(all) {
+			end = len(all)
+		}
+
+		var wg sync.WaitGroup
+		errs := make([]error, end-start)
+		for n, sh := range all[start:end] {
+			wg.Add(1)
+			go func(n int, sh *shard) {
+				defer wg.Done()
+				if err := sh.session.Open(); err != nil {
+					errs[n] = fmt.Errorf("shard %d: %w", sh.session.ShardID, err)
+				}
+			}(n, sh)
+		}
+		wg.Wait()
+
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close disconnects every shard
+func Close() error {
+	var errs []error
+	for _, s := range Sessions() {
+		if err := s.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("shard %d: %w", s.ShardID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Count returns how many shards we are running
+func Count() int {
+	lock.RLock()
+	defer lock.RUnlock()
+	return len(shards)
+}
+
+// Sessions returns the session of every shard in shard order
+func Sessions() []*discordgo.Session {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	sessions := make([]*discordgo.Session, 0, len(shards))
+	for _, sh := range shards {
+		sessions = append(sessions, sh.session)
+	}
+	return sessions
+}
+
+// Primary returns shard 0, use it for anything that isnt tied to a guild (like registering commands)
+func Primary() *discordgo.Session {
+	lock.RLock()
+	defer lock.RUnlock()
+	if len(shards) == 0 {
+		return nil
+	}
+	return shards[0].session
+}
+
+// Statuses returns a snapshot of every shard
+func Statuses() []Status {
+	lock.RLock()
+	all := append([]*shard(nil), shards...)
+	lock.RUnlock()
+
+	statuses := make([]Status, 0, len(all))
+	for _, sh := range all {
+		statuses = append(statuses, sh.status())
+	}
+	return statuses
+}
+
+// status builds the snapshot for a shard
+func (sh *shard) status() Status {
+	s := sh.session
+
+	sh.lock.RLock()
+	st := Status{ID: s.ShardID, Ready: sh.ready, ReadyAt: sh.readyAt}
+	sh.lock.RUnlock()
+
+	s.RLock()
+	st.LastHeartbeatAck = s.LastHeartbeatAck
+	s.RUnlock()
+	st.Latency = s.HeartbeatLatency()
+
+	s.State.RLock()
+	st.Guilds = len(s.State.Guilds)
+	s.State.RUnlock()
+	return st
+}
+
+// onReady marks the shard as ready
+func (sh *shard) onReady(_ *discordgo.Session, _ *discordgo.Ready) {
+	sh.lock.Lock()
+	defer sh.lock.Unlock()
+	sh.ready = true
+	sh.readyAt = time.Now()
+}
+
+// onResumed marks the shard as ready again after it resumed its old session (no READY is sent for those)
+func (sh *shard) onResumed(_ *discordgo.Session, _ *discordgo.Resumed) {
+	sh.lock.Lock()
+	defer sh.lock.Unlock()
+	sh.ready = true
+}
+
+// onDisconnect marks the shard as not ready until the next READY
+func (sh *shard) onDisconnect(_ *discordgo.Session, _ *discordgo.Disconnect) {
+	sh.lock.Lock()
+	defer sh.lock.Unlock()
+	sh.ready = false
+}
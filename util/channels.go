@@ -0,0 +1,17 @@
+package util
+
+import "github.com/bwmarrin/discordgo"
+
+// ChannelIDs returns the channel plus, for a thread, the channel it was started in
+// anything set for a channel covers its threads too, so checks should look at every ID this returns
+func ChannelIDs(s *discordgo.Session, channelID string) []string {
+	if channelID == "" {
+		return nil
+	}
+	ids := []string{channelID}
+	// DMs and channels we dont have cached just get checked by their ID
+	if channel, err := s.State.Channel(channelID); err == nil && channel.IsThread() {
+		ids = append(ids, channel.ParentID)
+	}
+	return ids
+}
@@ -0,0 +1,91 @@
+package util
+
+import (
+	"context"
+	"template/logging"
+	"template/settings"
+	"time"
+)
+
+// ResponseWindow is how long Discord waits for the first response to an interaction
+// before it shows "The application did not respond", anything slower has to Defer first
+const ResponseWindow = 3 * time.Second
+
+// DefaultCommandTimeout is how long a command can run when the config doesnt say
+const DefaultCommandTimeout = time.Minute
+
+// Invocation is the request scoped info every command gets through its context
+type Invocation struct {
+	ID        string          // the ID of the message or interaction that triggered the command
+	Command   string          // command name
+	Type      string          // prefix or slash
+	Started   time.Time       // when we started handling it
+	RespondBy time.Time       // when the interaction response window closes, zero for prefix commands
+	Logger    *logging.Logger // logger with the command, user, guild and channel fields already attached
+	Guild     settings.Guild  // resolved settings for the guild the command was used in
+}
+
+// invocationKey is the context key the invocation is stored under
+type invocationKey struct{}
+
+// NewCommandContext returns the context a command runs with, its canceled when the command times out
+// or the bot stops waiting for commands during shutdown (whichever comes first)
+func NewCommandContext(parent context.Context, inv *Invocation, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = DefaultCommandTimeout
+	}
+	if inv.Started.IsZero() {
+		inv.Started = time.Now()
+	}
+	if inv.Logger == nil {
+		inv.Logger = logging.With(nil)
+	}
+
+	ctx := context.WithValue(parent, invocationKey{}, inv)
+	return context.WithDeadline(ctx, inv.Started.Add(timeout))
+}
+
+// InvocationFrom returns the invocation stored in the context, nil when there isnt one
+func InvocationFrom(ctx context.Context) *Invocation {
+	inv, _ := ctx.Value(invocationKey{}).(*Invocation)
+	return inv
+}
+
+// InvocationID returns the ID of the invocation that owns the context
+func InvocationID(ctx context.Context) string {
+	if inv := InvocationFrom(ctx); inv != nil {
+		return inv.ID
+	}
+	return ""
+}
+
+// Logger returns the invocation's logger so every line can be tied back to the command, falls back to a plain logger
+func Logger(ctx context.Context) *logging.Logger {
+	if inv := InvocationFrom(ctx); inv != nil && inv.Logger != nil {
+		return inv.Logger
+	}
+	return logging.With(nil)
+}
+
+// GuildSettings returns the resolved settings for the guild the command was used in
+func GuildSettings(ctx context.Context) settings.Guild {
+	if inv := InvocationFrom(ctx); inv != nil {
+		return inv.Guild
+	}
+	return settings.Resolve("")
+}
+
+// RespondBy returns when the interaction response window closes, ok is false for prefix commands
+// commands that might take longer than that should Defer before doing the slow part
+func RespondBy(ctx context.Context) (deadline time.Time, ok bool) {
+	if inv := InvocationFrom(ctx); inv != nil && !inv.RespondBy.IsZero() {
+		return inv.RespondBy, true
+	}
+	return time.Time{}, false
+}
+
+// ShouldDefer reports whether work that takes about this long would miss the interaction response window
+func ShouldDefer(ctx context.Context, work time.Duration) bool {
+	deadline, ok := RespondBy(ctx)
+	return ok && time.Now().Add(work).After(deadline)
+}
@@ -124,6 +124,18 @@ func (e *Embed) SetFooter(args ...string) *Embed {
 	return e
 }
 
+// AppendFooter adds text to the end of the footer with a " • " between, embeds without a footer just get one
+func (e *Embed) AppendFooter(text string) *Embed {
+	if e.Footer == nil {
+		return e.SetFooter(text)
+	}
+	if e.Footer.Text != "" {
+		text = e.Footer.Text + " • " + text
+	}
+	e.Footer.Text = text
+	return e
+}
+
 // SetImage ...
 func (e *Embed) SetImage(args ...string) *Embed {
 	var URL string
@@ -276,22 +288,14 @@ func (e *Embed) TruncateFooter() *Embed {
 	return e
 }
 
-// NewGenericEmbed creates a new generic embed
+// NewGenericEmbed creates a new generic embed (uses the info theme color)
 func NewGenericEmbed(embedTitle, embedMsg string, replacements ...interface{}) *discordgo.MessageEmbed {
-	genericEmbed := NewEmbed().
-		SetTitle(embedTitle).
-		SetDescription(fmt.Sprintf(embedMsg, replacements...)).
-		SetColor(28, 28, 28).MessageEmbed // #1c1c1c - Dark gray
-	return genericEmbed
+	return newThemedMessage(ThemeInfo, embedTitle, embedMsg, replacements...)
 }
 
-// NewErrorEmbed creates a new error embed
+// NewErrorEmbed creates a new error embed (uses the error theme color)
 func NewErrorEmbed(errorTitle, errorMsg string, replacements ...interface{}) *discordgo.MessageEmbed {
-	errorEmbed := NewEmbed().
-		SetTitle(errorTitle).
-		SetDescription(fmt.Sprintf(errorMsg, replacements...)).
-		SetColor(180, 0, 0).MessageEmbed
-	return errorEmbed
+	return newThemedMessage(ThemeError, errorTitle, errorMsg, replacements...)
 }
 
 // NewErrorEmbedAdvanced creates a new error embed with a custom RGB color
@@ -303,8 +307,8 @@ func NewErrorEmbedAdvanced(errorTitle, errorMsg string, r, g, b int) *discordgo.
 	return errorEmbed
 }
 
-// EasyError sends a simple error message to the channel
+// EasyError sends a simple error message to the channel (use Responder.Error for slash commands)
 func EasyError(s *discordgo.Session, m *discordgo.MessageCreate, title string, data ...interface{}) {
 	message := fmt.Sprint(data...)
-	s.ChannelMessageSendEmbed(m.ChannelID, NewErrorEmbed(title, "%s", message))
+	NewMessageResponder(s, m.Message).Error(title, "%s", message)
 }
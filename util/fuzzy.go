@@ -0,0 +1,83 @@
+package util
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Distance returns how many single character edits (insert, delete, replace or swapping two neighbours) it takes
+// to turn a into b, ignoring case, so "pnig" is 1 away from "ping"
+func Distance(a, b string) int {
+	ra := []rune(strings.ToLower(a))
+	rb := []rune(strings.ToLower(b))
+
+	// we keep the last three rows around, the swap check needs the row before the previous one
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+// Closest returns up to max candidates that look like query, closest first
+// short queries only allow a single typo so "ab" doesnt suggest half the command list, and a query that is the
+// start of a candidate (like "conf" for "config") always counts
+func Closest(query string, candidates []string, max int) []string {
+	allowed := 1
+	if n := utf8.RuneCountInString(query); n > 4 {
+		allowed = n / 3
+		if allowed > 3 {
+			allowed = 3
+		}
+	}
+
+	type match struct {
+		name     string
+		distance int
+	}
+	var matches []match
+	for _, c := range candidates {
+		d := Distance(query, c)
+		if utf8.RuneCountInString(query) >= 3 && strings.HasPrefix(strings.ToLower(c), strings.ToLower(query)) {
+			// typing the start of a name is a pretty strong hint, we rank it like a single typo
+			d = min(d, 1)
+		}
+		if d <= allowed {
+			matches = append(matches, match{c, d})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].name < matches[j].name
+	})
+
+	var out []string
+	for _, m := range matches {
+		if len(out) == max {
+			break
+		}
+		out = append(out, m.name)
+	}
+	return out
+}
@@ -0,0 +1,29 @@
+package util
+
+import "strings"
+
+// ParseChannelMention returns the ID from a channel mention (<#123>) or a plain ID
+func ParseChannelMention(s string) (string, bool) {
+	return parseMention(s, "<#")
+}
+
+// ParseRoleMention returns the ID from a role mention (<@&123>) or a plain ID
+func ParseRoleMention(s string) (string, bool) {
+	return parseMention(s, "<@&")
+}
+
+// parseMention strips the mention wrapping off an ID, plain IDs are passed through as long as they are all digits
+func parseMention(s, open string) (string, bool) {
+	if strings.HasPrefix(s, open) && strings.HasSuffix(s, ">") {
+		s = strings.TrimSuffix(strings.TrimPrefix(s, open), ">")
+	}
+	if s == "" {
+		return "", false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return "", false
+		}
+	}
+	return s, true
+}
@@ -0,0 +1,459 @@
+package util
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"template/logging"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// PageRenderer builds the embed for a single page (pages start at 0)
+type PageRenderer func(page int) *discordgo.MessageEmbed
+
+// Paginator is a generic button/select menu paginator that works for both prefix and slash commands
+type Paginator struct {
+	ID        string                    // unique ID used in the component custom IDs
+	Pages     []*discordgo.MessageEmbed // static pages, used when Render is nil
+	PageCount int                       // how many pages Render can build
+	Render    PageRenderer              // optional data source, lets us build pages lazily
+	OwnerID   string                    // only this user can use the controls, leave empty to let anyone use them
+	Timeout   time.Duration             // how long the controls stay active after the last interaction
+	Page      int                       // the page we are currently showing
+
+	lock        sync.Mutex
+	session     *discordgo.Session
+	channelID   string
+	messageID   string
+	interaction *discordgo.Interaction // the latest interaction for the message, its token is how we edit ephemeral menus
+	ephemeral   bool                   // ephemeral menus arent channel messages so they can only be edited through a token
+	timer       *time.Timer
+	deadline    time.Time // when the controls expire, every click moves it
+	closed      bool
+}
+
+// PaginatorPrefix is the custom ID prefix for all paginator components
+const PaginatorPrefix = "paginator:"
+
+// DefaultPaginatorTimeout is how long a paginator stays active when no timeout is set
+const DefaultPaginatorTimeout = 5 * time.Minute
+
+// maxInteractionTimeout is the longest an interaction paginator can go without a click, interaction tokens die
+// after 15 minutes and an ephemeral menu cant be edited (or have its controls disabled) without one
+const maxInteractionTimeout = 14 * time.Minute
+
+// paginators keeps track of all active paginators by ID so the component handler can find them
+var (
+	paginators     = make(map[string]*Paginator)
+	paginatorsLock sync.Mutex
+)
+
+// NewPaginator returns a paginator for a fixed list of embeds
+func NewPaginator(pages ...*discordgo.MessageEmbed) *Paginator {
+	return &Paginator{
+		ID:        newPaginatorID(),
+		Pages:     pages,
+		PageCount: len(pages),
+		Timeout:   DefaultPaginatorTimeout,
+	}
+}
+
+// NewPaginatorFunc returns a paginator that renders each page on demand
+func NewPaginatorFunc(count int, render PageRenderer) *Paginator {
+	return &Paginator{
+		ID:        newPaginatorID(),
+		PageCount: count,
+		Render:    render,
+		Timeout:   DefaultPaginatorTimeout,
+	}
+}
+
+// SetOwner restricts the controls to a single user
+func (p *Paginator) SetOwner(userID string) *Paginator {
+	p.OwnerID = userID
+	return p
+}
+
+// SetTimeout sets how long the controls stay active after the last interaction
+func (p *Paginator) SetTimeout(timeout time.Duration) *Paginator {
+	p.Timeout = timeout
+	return p
+}
+
+// SetPage sets the page the paginator opens on
+func (p *Paginator) SetPage(page int) *Paginator {
+	p.Page = page
+	return p
+}
+
+// Send sends the paginator as a normal channel message (for prefix commands)
+func (p *Paginator) Send(s *discordgo.Session, channelID string) (*discordgo.Message, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	embed, components := p.render()
+	msg, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{embed},
+		Components: components,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.session = s
+	p.channelID = channelID
+	p.messageID = msg.ID
+	p.activate()
+	return msg, nil
+}
+
+// RespondDeferred fills in an interaction response that was already deferred with the paginator, for commands that
+// need to do slow work (like reading a big file) before they know what the pages are
+// ephemeral has to match what was passed to Defer
+func (p *Paginator) RespondDeferred(s *discordgo.Session, i *discordgo.Interaction, ephemeral bool) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	embed, components := p.render()
+	embeds := []*discordgo.MessageEmbed{embed}
+	msg, err := s.InteractionResponseEdit(i, &discordgo.WebhookEdit{
+		Embeds:     &embeds,
+		Components: &components,
+	})
+	if err != nil {
+		return err
+	}
+
+	p.session = s
+	p.interaction = i
+	p.ephemeral = ephemeral
+	p.channelID = msg.ChannelID
+	p.messageID = msg.ID
+	p.activate()
+	return nil
+}
+
+// Respond sends the paginator as the response to an interaction (for slash commands)
+func (p *Paginator) Respond(s *discordgo.Session, i *discordgo.Interaction, ephemeral bool) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	embed, components := p.render()
+	data := &discordgo.InteractionResponseData{
+		Embeds:     []*discordgo.MessageEmbed{embed},
+		Components: components,
+	}
+	if ephemeral {
+		data.Flags = discordgo.MessageFlagsEphemeral
+	}
+
+	err := s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: data,
+	})
+	if err != nil {
+		return err
+	}
+
+	p.session = s
+	p.interaction = i
+	p.ephemeral = ephemeral
+	p.channelID = i.ChannelID
+	p.activate()
+	return nil
+}
+
+// Close stops the paginator and disables its controls
+func (p *Paginator) Close() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.expire()
+}
+
+// ClosePaginators closes every active paginator so their controls get disabled, call this on shutdown
+// while the session is still open otherwise the buttons stay clickable with nothing behind them
+func ClosePaginators() {
+	paginatorsLock.Lock()
+	active := make([]*Paginator, 0, len(paginators))
+	for _, p := range paginators {
+		active = append(active, p)
+	}
+	paginatorsLock.Unlock()
+
+	for _, p := range active {
+		p.Close()
+	}
+}
+
+// activate registers the paginator and starts the expiry timer (caller must hold the lock)
+func (p *Paginator) activate() {
+	// theres nothing to paginate so we dont need to track it
+	if p.PageCount <= 1 {
+		return
+	}
+
+	if p.Timeout <= 0 {
+		p.Timeout = DefaultPaginatorTimeout
+	}
+	if p.interaction != nil && p.Timeout > maxInteractionTimeout {
+		p.Timeout = maxInteractionTimeout
+	}
+
+	paginatorsLock.Lock()
+	paginators[p.ID] = p
+	paginatorsLock.Unlock()
+
+	p.deadline = time.Now().Add(p.Timeout)
+	p.timer = time.AfterFunc(p.Timeout, p.checkExpiry)
+}
+
+// checkExpiry runs when the timer fires, clicks only move the deadline so we check it under the lock
+// and go back to sleep if someone used the menu in the meantime (resetting a timer that already fired cant stop it)
+func (p *Paginator) checkExpiry() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.closed {
+		return
+	}
+	if left := time.Until(p.deadline); left > 0 {
+		p.timer.Reset(left)
+		return
+	}
+	p.expire()
+}
+
+// expire removes the paginator and edits the message so the controls are disabled (caller must hold the lock)
+func (p *Paginator) expire() {
+	if p.closed {
+		return
+	}
+	p.closed = true
+
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+
+	paginatorsLock.Lock()
+	delete(paginators, p.ID)
+	paginatorsLock.Unlock()
+
+	if p.session == nil {
+		return
+	}
+
+	embed, components := p.render()
+	embeds := []*discordgo.MessageEmbed{embed}
+	// ephemeral menus (and ones nobody clicked yet so we dont know the message) go through the latest interaction token,
+	// every click gives us a fresh one so it is always younger than the timeout
+	if p.interaction != nil && (p.ephemeral || p.messageID == "") {
+		if _, err := p.session.InteractionResponseEdit(p.interaction, &discordgo.WebhookEdit{
+			Embeds:     &embeds,
+			Components: &components,
+		}); err != nil {
+			logging.Warn("Failed to disable the controls of paginator %s: %v", p.ID, err)
+		}
+		return
+	}
+
+	_, err := p.session.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:         p.messageID,
+		Channel:    p.channelID,
+		Embeds:     &embeds,
+		Components: &components,
+	})
+	if err != nil {
+		logging.Warn("Failed to disable the controls of paginator %s: %v", p.ID, err)
+	}
+}
+
+// page returns the embed for the given page
+func (p *Paginator) page(page int) *discordgo.MessageEmbed {
+	if p.Render != nil {
+		return p.Render(page)
+	}
+	if page >= len(p.Pages) {
+		// nothing to show so we send an empty embed instead of crashing
+		return NewEmbed().SetDescription("Nothing to show here").MessageEmbed
+	}
+	return p.Pages[page]
+}
+
+// render builds the current page and the controls under it (caller must hold the lock)
+func (p *Paginator) render() (*discordgo.MessageEmbed, []discordgo.MessageComponent) {
+	// make sure we dont go out of bounds
+	last := p.PageCount - 1
+	if p.Page > last {
+		p.Page = last
+	}
+	if p.Page < 0 {
+		p.Page = 0
+	}
+
+	embed := p.page(p.Page)
+	if p.PageCount <= 1 {
+		return embed, []discordgo.MessageComponent{}
+	}
+
+	disabled := p.closed
+	buttons := discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.Button{CustomID: p.customID("first"), Label: "⏮", Style: discordgo.SecondaryButton, Disabled: disabled || p.Page == 0},
+			discordgo.Button{CustomID: p.customID("prev"), Label: "◀ Previous", Style: discordgo.SecondaryButton, Disabled: disabled || p.Page == 0},
+			discordgo.Button{CustomID: p.customID("next"), Label: "Next ▶", Style: discordgo.SecondaryButton, Disabled: disabled || p.Page >= last},
+			discordgo.Button{CustomID: p.customID("last"), Label: "⏭", Style: discordgo.SecondaryButton, Disabled: disabled || p.Page >= last},
+			discordgo.Button{CustomID: p.customID("close"), Label: "✖ Close", Style: discordgo.DangerButton, Disabled: disabled},
+		},
+	}
+
+	// select menus can only hold 25 options so we show a window around the current page
+	start := p.Page - 12
+	if start > p.PageCount-25 {
+		start = p.PageCount - 25
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := start + 25
+	if end > p.PageCount {
+		end = p.PageCount
+	}
+
+	options := make([]discordgo.SelectMenuOption, 0, end-start)
+	for n := start; n < end; n++ {
+		options = append(options, discordgo.SelectMenuOption{
+			Label:   fmt.Sprintf("Page %d", n+1),
+			Value:   strconv.Itoa(n),
+			Default: n == p.Page,
+		})
+	}
+
+	jump := discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{
+				MenuType:    discordgo.StringSelectMenu,
+				CustomID:    p.customID("jump"),
+				Placeholder: fmt.Sprintf("Page %d/%d", p.Page+1, p.PageCount),
+				Options:     options,
+				Disabled:    disabled,
+			},
+		},
+	}
+
+	return embed, []discordgo.MessageComponent{buttons, jump}
+}
+
+// customID builds the custom ID for one of our controls (paginator:<id>:<action>)
+func (p *Paginator) customID(action string) string {
+	return PaginatorPrefix + p.ID + ":" + action
+}
+
+// HandlePaginator handles button and select menu interactions for every active paginator
+func HandlePaginator(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionMessageComponent {
+		return
+	}
+
+	// make sure its one of ours since other components come through here too
+	data := i.MessageComponentData()
+	if !strings.HasPrefix(data.CustomID, PaginatorPrefix) {
+		return
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(data.CustomID, PaginatorPrefix), ":", 2)
+	if len(parts) != 2 {
+		return
+	}
+
+	paginatorsLock.Lock()
+	p, ok := paginators[parts[0]]
+	paginatorsLock.Unlock()
+
+	if !ok {
+		NewInteractionResponder(s, i.Interaction).Text("❌ This menu has expired, run the command again to get a new one.", true)
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.closed {
+		NewInteractionResponder(s, i.Interaction).Text("❌ This menu has expired, run the command again to get a new one.", true)
+		return
+	}
+
+	// only the person who opened the menu can use it (unless no owner was set)
+	if p.OwnerID != "" && InteractionUserID(i.Interaction) != p.OwnerID {
+		NewInteractionResponder(s, i.Interaction).Text("❌ Only the user who opened this menu can use these controls.", true)
+		return
+	}
+
+	// the click is the newest interaction on the message, so its token is the one we disable the controls with later
+	p.interaction = i.Interaction
+	if i.Message != nil {
+		p.channelID = i.Message.ChannelID
+		p.messageID = i.Message.ID
+	}
+
+	switch parts[1] {
+	case "first":
+		p.Page = 0
+	case "prev":
+		p.Page--
+	case "next":
+		p.Page++
+	case "last":
+		p.Page = p.PageCount - 1
+	case "jump":
+		if len(data.Values) > 0 {
+			if page, err := strconv.Atoi(data.Values[0]); err == nil {
+				p.Page = page
+			}
+		}
+	case "close":
+		// we acknowledge the click first then strip the controls
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate})
+		p.expire()
+		return
+	}
+
+	// every interaction pushes the expiry back so active menus dont die mid use
+	p.deadline = time.Now().Add(p.Timeout)
+
+	embed, components := p.render()
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: components,
+		},
+	})
+}
+
+// InteractionUser returns the user who triggered an interaction (guild or DM)
+func InteractionUser(i *discordgo.Interaction) *discordgo.User {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User
+	}
+	return i.User
+}
+
+// InteractionUserID returns the ID of the user who triggered an interaction (guild or DM)
+func InteractionUserID(i *discordgo.Interaction) string {
+	if user := InteractionUser(i); user != nil {
+		return user.ID
+	}
+	return ""
+}
+
+// newPaginatorID returns a short random ID for a paginator
+func newPaginatorID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,241 @@
+package util
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Response is a message we want to send back, it works the same for prefix and slash commands
+type Response struct {
+	Content    string
+	Embeds     []*discordgo.MessageEmbed
+	Components []discordgo.MessageComponent
+	Files      []*discordgo.File // attachments
+	Ephemeral  bool              // only the user can see it (prefix commands fake this with a temporary message)
+}
+
+// Responder sends responses for a command without caring how the command was invoked
+type Responder struct {
+	Session     *discordgo.Session
+	Interaction *discordgo.Interaction // set for slash commands
+	Message     *discordgo.Message     // set for prefix commands, this is the message that invoked the command
+
+	lock     sync.Mutex
+	replied  bool               // we already sent (or deferred) the first response
+	deferred bool               // the first response was deferred and still needs to be filled in
+	original *discordgo.Message // the first message we sent for prefix commands
+}
+
+// ErrNoResponse is returned when editing or deleting before anything was sent
+var ErrNoResponse = errors.New("no response has been sent yet")
+
+// NewInteractionResponder returns a responder for a slash command (or any other interaction)
+func NewInteractionResponder(s *discordgo.Session, i *discordgo.Interaction) *Responder {
+	return &Responder{Session: s, Interaction: i}
+}
+
+// NewMessageResponder returns a responder for a prefix command
+func NewMessageResponder(s *discordgo.Session, m *discordgo.Message) *Responder {
+	return &Responder{Session: s, Message: m}
+}
+
+// ChannelID returns the channel the command was used in
+func (r *Responder) ChannelID() string {
+	if r.Interaction != nil {
+		return r.Interaction.ChannelID
+	}
+	return r.Message.ChannelID
+}
+
+// Reply sends the first response, if it was deferred this fills in the deferred response instead
+func (r *Responder) Reply(resp *Response) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	// replying twice is really a follow up, so we treat it like one instead of letting discord reject it
+	if r.replied && !r.deferred {
+		_, err := r.followUp(resp)
+		return err
+	}
+
+	if r.Interaction != nil {
+		if r.deferred {
+			r.deferred = false
+			_, err := r.Session.InteractionResponseEdit(r.Interaction, webhookEdit(resp))
+			return err
+		}
+
+		r.replied = true
+		return r.Session.InteractionRespond(r.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content:    resp.Content,
+				Embeds:     resp.Embeds,
+				Components: resp.Components,
+				Files:      resp.Files,
+				Flags:      flags(resp),
+			},
+		})
+	}
+
+	r.replied = true
+	r.deferred = false
+	msg, err := r.send(resp)
+	if err != nil {
+		return err
+	}
+	r.original = msg
+	return nil
+}
+
+// Defer tells Discord we need more than 3 seconds, prefix commands just show the typing indicator
+func (r *Responder) Defer(ephemeral bool) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.replied {
+		return nil
+	}
+	r.replied = true
+	r.deferred = true
+
+	if r.Interaction != nil {
+		resp := &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredChannelMessageWithSource}
+		if ephemeral {
+			resp.Data = &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral}
+		}
+		return r.Session.InteractionRespond(r.Interaction, resp)
+	}
+	return r.Session.ChannelTyping(r.Message.ChannelID)
+}
+
+// FollowUp sends another message after the first response
+func (r *Responder) FollowUp(resp *Response) (*discordgo.Message, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.followUp(resp)
+}
+
+// followUp sends a follow up message (caller must hold the lock)
+func (r *Responder) followUp(resp *Response) (*discordgo.Message, error) {
+	if r.Interaction != nil {
+		return r.Session.FollowupMessageCreate(r.Interaction, true, &discordgo.WebhookParams{
+			Content:    resp.Content,
+			Embeds:     resp.Embeds,
+			Components: resp.Components,
+			Files:      resp.Files,
+			Flags:      flags(resp),
+		})
+	}
+	return r.send(resp)
+}
+
+// Edit edits the first response
+func (r *Responder) Edit(resp *Response) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.Interaction != nil {
+		if !r.replied {
+			return ErrNoResponse
+		}
+		r.deferred = false
+		_, err := r.Session.InteractionResponseEdit(r.Interaction, webhookEdit(resp))
+		return err
+	}
+
+	if r.original == nil {
+		return ErrNoResponse
+	}
+
+	edit := discordgo.NewMessageEdit(r.original.ChannelID, r.original.ID).SetContent(resp.Content)
+	edit.Embeds = &resp.Embeds
+	edit.Files = resp.Files
+	if resp.Components != nil {
+		edit.Components = &resp.Components
+	}
+	msg, err := r.Session.ChannelMessageEditComplex(edit)
+	if err == nil {
+		r.original = msg
+	}
+	return err
+}
+
+// Delete deletes the first response
+func (r *Responder) Delete() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.Interaction != nil {
+		if !r.replied {
+			return ErrNoResponse
+		}
+		return r.Session.InteractionResponseDelete(r.Interaction)
+	}
+
+	if r.original == nil {
+		return ErrNoResponse
+	}
+	err := r.Session.ChannelMessageDelete(r.original.ChannelID, r.original.ID)
+	if err == nil {
+		r.original = nil
+	}
+	return err
+}
+
+// Text replies with a plain text message
+func (r *Responder) Text(content string, ephemeral bool) error {
+	return r.Reply(&Response{Content: content, Ephemeral: ephemeral})
+}
+
+// Embed replies with a single embed
+func (r *Responder) Embed(embed *discordgo.MessageEmbed, ephemeral bool) error {
+	return r.Reply(&Response{Embeds: []*discordgo.MessageEmbed{embed}, Ephemeral: ephemeral})
+}
+
+// Error replies with an error embed, slash commands get it as an ephemeral message
+func (r *Responder) Error(title, msg string, replacements ...interface{}) error {
+	return r.Reply(&Response{Embeds: []*discordgo.MessageEmbed{NewErrorEmbed(title, msg, replacements...)}, Ephemeral: r.Interaction != nil})
+}
+
+// send sends a normal channel message for prefix commands, ephemeral ones get deleted after a few seconds
+func (r *Responder) send(resp *Response) (*discordgo.Message, error) {
+	msg, err := r.Session.ChannelMessageSendComplex(r.Message.ChannelID, &discordgo.MessageSend{
+		Content:    resp.Content,
+		Embeds:     resp.Embeds,
+		Components: resp.Components,
+		Files:      resp.Files,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// normal channels dont support ephemeral messages so we mimic it by deleting the message after a bit
+	if resp.Ephemeral {
+		ScheduleDelete(r.Session, msg.ChannelID, msg.ID, DefaultTempTTL)
+	}
+	return msg, nil
+}
+
+// webhookEdit turns a response into the edit payload for an interaction response
+func webhookEdit(resp *Response) *discordgo.WebhookEdit {
+	edit := &discordgo.WebhookEdit{
+		Content: &resp.Content,
+		Embeds:  &resp.Embeds,
+		Files:   resp.Files,
+	}
+	if resp.Components != nil {
+		edit.Components = &resp.Components
+	}
+	return edit
+}
+
+// flags returns the message flags for a response
+func flags(resp *Response) discordgo.MessageFlags {
+	if resp.Ephemeral {
+		return discordgo.MessageFlagsEphemeral
+	}
+	return 0
+}
@@ -0,0 +1,312 @@
+package templates
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"template/config"
+	"template/logging"
+	"template/settings"
+	"template/util"
+	"text/template"
+
+	"github.com/bwmarrin/discordgo"
+	"gopkg.in/yaml.v3"
+)
+
+// Dir is where we look for embed template files
+const Dir = "./config/templates"
+
+// Data is what gets passed into every template, so you can use {{.User.Username}}, {{.Brand.Name}}, {{.Args.anything}} and so on
+type Data struct {
+	User   *discordgo.User
+	Member *discordgo.Member
+	Guild  *discordgo.Guild
+	Brand  config.BrandConfig
+	Prefix string
+	Args   map[string]any
+}
+
+// EmbedTemplate is the layout of a template file, every string can use text/template placeholders
+type EmbedTemplate struct {
+	Title       string          `json:"title" yaml:"title"`
+	Description string          `json:"description" yaml:"description"`
+	URL         string          `json:"url" yaml:"url"`
+	Color       string          `json:"color" yaml:"color"` // theme name (primary, success..), named color (red, blurple..) or hex
+	Thumbnail   string          `json:"thumbnail" yaml:"thumbnail"`
+	Image       string          `json:"image" yaml:"image"`
+	Author      *AuthorTemplate `json:"author" yaml:"author"`
+	Footer      *FooterTemplate `json:"footer" yaml:"footer"`
+	Fields      []FieldTemplate `json:"fields" yaml:"fields"`
+}
+
+// AuthorTemplate is the author block of an embed template
+type AuthorTemplate struct {
+	Name string `json:"name" yaml:"name"`
+	Icon string `json:"icon" yaml:"icon"`
+	URL  string `json:"url" yaml:"url"`
+}
+
+// FooterTemplate is the footer of an embed template
+type FooterTemplate struct {
+	Text string `json:"text" yaml:"text"`
+	Icon string `json:"icon" yaml:"icon"`
+}
+
+// FieldTemplate is a single field of an embed template
+type FieldTemplate struct {
+	Name   string `json:"name" yaml:"name"`
+	Value  string `json:"value" yaml:"value"`
+	Inline bool   `json:"inline" yaml:"inline"`
+}
+
+// compiled holds a template file after all its placeholders have been parsed
+type compiled struct {
+	def   EmbedTemplate
+	parts map[string]*template.Template
+}
+
+var (
+	registry = make(map[string]*compiled)
+	lock     sync.RWMutex
+)
+
+// funcs are the extra helpers templates can use on top of the text/template builtins
+var funcs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join":  strings.Join,
+}
+
+// NewData returns template data for a user in a guild, the guild is looked up in the session state
+func NewData(s *discordgo.Session, guildID string, user *discordgo.User) Data {
+	data := Data{
+		User:   user,
+		Brand:  config.Config.Brand,
+		Prefix: settings.Resolve(guildID).Prefix, // guilds can have their own prefix
+		Args:   make(map[string]any),
+	}
+
+	if guildID != "" && s != nil {
+		if guild, err := s.State.Guild(guildID); err == nil {
+			data.Guild = guild
+		}
+	}
+	return data
+}
+
+// With adds a value to the template args and returns the data so calls can be chained
+func (d Data) With(key string, value any) Data {
+	if d.Args == nil {
+		d.Args = make(map[string]any)
+	}
+	d.Args[key] = value
+	return d
+}
+
+// Load reads every .json, .yaml and .yml file in the templates directory into the registry
+func Load() {
+	loaded, err := loadDir()
+	if err != nil {
+		// a broken template is a broken response so we stop here like we do for a broken config
+		logging.Error("Failed to load embed template %v", err)
+		os.Exit(1)
+	}
+	if len(loaded) == 0 {
+		logging.Warn("No embed templates found in %s", Dir)
+		return
+	}
+
+	lock.Lock()
+	registry = loaded
+	lock.Unlock()
+
+	logging.Info("Loaded %d embed templates", len(loaded))
+}
+
+// Check compiles every template file without loading them, it returns how many there are or the first broken one
+func Check() (int, error) {
+	loaded, err := loadDir()
+	return len(loaded), err
+}
+
+// extensions are the template files we read, the extension decides how the file is parsed
+var extensions = []string{".json", ".yaml", ".yml"}
+
+// loadDir compiles every template file in the templates directory
+func loadDir() (map[string]*compiled, error) {
+	var files []string
+	for _, ext := range extensions {
+		matches, err := filepath.Glob(filepath.Join(Dir, "*"+ext))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+
+	loaded := make(map[string]*compiled)
+	from := make(map[string]string)
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		// uptime.json and uptime.yaml would both be "uptime", we cant guess which one was meant
+		if other, ok := from[name]; ok {
+			return nil, fmt.Errorf("%s: template %q is already defined in %s", file, name, other)
+		}
+		from[name] = file
+
+		t, err := loadFile(name, file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		loaded[name] = t
+	}
+	return loaded, nil
+}
+
+// loadFile parses a single template file and compiles all its placeholders
+func loadFile(name, file string) (*compiled, error) {
+	f, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &compiled{parts: make(map[string]*template.Template)}
+	if filepath.Ext(file) == ".json" {
+		err = json.Unmarshal(f, &t.def)
+	} else {
+		err = yaml.Unmarshal(f, &t.def)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// we compile every part up front so typos show up at startup instead of when someone runs the command
+	add := func(part, text string) error {
+		if text == "" {
+			return nil
+		}
+		// an arg the command didnt pass is an error instead of "<no value>" in the embed, optional args get passed as ""
+		tpl, err := template.New(name + "." + part).Funcs(funcs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return err
+		}
+		t.parts[part] = tpl
+		return nil
+	}
+
+	parts := map[string]string{
+		"title":       t.def.Title,
+		"description": t.def.Description,
+		"url":         t.def.URL,
+		"color":       t.def.Color,
+		"thumbnail":   t.def.Thumbnail,
+		"image":       t.def.Image,
+	}
+	if t.def.Author != nil {
+		parts["author.name"] = t.def.Author.Name
+		parts["author.icon"] = t.def.Author.Icon
+		parts["author.url"] = t.def.Author.URL
+	}
+	if t.def.Footer != nil {
+		parts["footer.text"] = t.def.Footer.Text
+		parts["footer.icon"] = t.def.Footer.Icon
+	}
+	for i, field := range t.def.Fields {
+		parts[fmt.Sprintf("fields.%d.name", i)] = field.Name
+		parts[fmt.Sprintf("fields.%d.value", i)] = field.Value
+	}
+
+	for part, text := range parts {
+		if err := add(part, text); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Has reports whether a template with the given name is loaded
+func Has(name string) bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	_, ok := registry[name]
+	return ok
+}
+
+// Execute renders a template into an embed and returns any error it runs into
+func Execute(name string, data Data) (*util.Embed, error) {
+	lock.RLock()
+	t, ok := registry[name]
+	lock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("template %q not found", name)
+	}
+
+	var renderErr error
+	exec := func(part string) string {
+		tpl, ok := t.parts[part]
+		if !ok {
+			return ""
+		}
+		var buf bytes.Buffer
+		if err := tpl.Execute(&buf, data); err != nil && renderErr == nil {
+			renderErr = err
+		}
+		return buf.String()
+	}
+
+	// we start from a themed embed so templates get the default footer/colors without repeating the brand everywhere
+	embed := util.NewThemedEmbed(util.ThemePrimary).
+		SetTitle(exec("title")).
+		SetDescription(exec("description")).
+		SetURL(exec("url")).
+		SetThumbnail(exec("thumbnail")).
+		SetImage(exec("image"))
+
+	if color := exec("color"); color != "" {
+		c, err := util.ParseColor(color)
+		if err != nil && renderErr == nil {
+			renderErr = err
+		}
+		embed.Color = c
+	}
+
+	if t.def.Author != nil {
+		embed.SetAuthor(exec("author.name"), exec("author.icon"), exec("author.url"))
+	}
+	if t.def.Footer != nil {
+		embed.SetFooter(exec("footer.text"), exec("footer.icon"))
+	}
+	for i, field := range t.def.Fields {
+		embed.AddField(exec(fmt.Sprintf("fields.%d.name", i)), exec(fmt.Sprintf("fields.%d.value", i)))
+		if field.Inline {
+			embed.Fields[len(embed.Fields)-1].Inline = true
+		}
+	}
+
+	// empty urls make discord reject the embed so we clear them out
+	if embed.Thumbnail != nil && embed.Thumbnail.URL == "" {
+		embed.Thumbnail = nil
+	}
+	if embed.Image != nil && embed.Image.URL == "" {
+		embed.Image = nil
+	}
+
+	if renderErr != nil {
+		return nil, fmt.Errorf("template %q: %w", name, renderErr)
+	}
+	return embed.Truncate(), nil
+}
+
+// Render renders a template into an embed, if anything goes wrong we log it and return an error embed instead
+func Render(name string, data Data) *util.Embed {
+	embed, err := Execute(name, data)
+	if err != nil {
+		logging.Error("Failed to render embed template: %v", err)
+		return &util.Embed{MessageEmbed: util.NewErrorEmbed("Error", "Something went wrong while building this response")}
+	}
+	return embed
+}
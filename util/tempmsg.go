@@ -0,0 +1,169 @@
+package util
+
+import (
+	"sync"
+	"template/logging"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// DefaultTempTTL is how long temporary messages stay up when no TTL is given
+const DefaultTempTTL = 5 * time.Second
+
+// these control the timer wheel, 120 slots of 500ms means one full turn of the wheel is a minute
+// anything longer than that just waits for a few extra turns
+const (
+	wheelTick  = 500 * time.Millisecond
+	wheelSlots = 120
+)
+
+// TempOptions controls how a temporary message behaves
+type TempOptions struct {
+	TTL           time.Duration      // how long the message stays up (defaults to 5 seconds)
+	Invoker       *discordgo.Message // the message that triggered this one
+	DeleteInvoker bool               // delete the invoking message along with ours
+}
+
+// pendingDelete is a message waiting in the wheel to be deleted
+type pendingDelete struct {
+	session   *discordgo.Session
+	channelID string
+	messageID string
+	rounds    int // how many more full turns of the wheel before this one is due
+}
+
+// tempWheel is a hashed timer wheel, one goroutine and one ticker handle every temporary message
+// instead of a goroutine (or a blocked handler) per message
+type tempWheel struct {
+	lock    sync.Mutex
+	slots   [wheelSlots][]*pendingDelete
+	pos     int
+	ticker  *time.Ticker
+	stop    chan struct{}
+	running bool
+}
+
+var wheel = &tempWheel{}
+
+// SendTemporary sends a text message that deletes itself after the TTL (mimics ephemeral messages for prefix commands)
+func SendTemporary(s *discordgo.Session, channelID, content string, opts TempOptions) (*discordgo.Message, error) {
+	return SendTemporaryComplex(s, channelID, &discordgo.MessageSend{Content: content}, opts)
+}
+
+// SendTemporaryComplex sends any message that deletes itself after the TTL
+func SendTemporaryComplex(s *discordgo.Session, channelID string, data *discordgo.MessageSend, opts TempOptions) (*discordgo.Message, error) {
+	msg, err := s.ChannelMessageSendComplex(channelID, data)
+	if err != nil {
+		return nil, err
+	}
+
+	ScheduleDelete(s, msg.ChannelID, msg.ID, opts.TTL)
+	if opts.DeleteInvoker && opts.Invoker != nil {
+		ScheduleDelete(s, opts.Invoker.ChannelID, opts.Invoker.ID, opts.TTL)
+	}
+	return msg, nil
+}
+
+// ScheduleDelete deletes a message after the TTL (defaults to 5 seconds)
+func ScheduleDelete(s *discordgo.Session, channelID, messageID string, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = DefaultTempTTL
+	}
+
+	ticks := int((ttl + wheelTick - 1) / wheelTick) // round up so we never delete early
+	if ticks < 1 {
+		ticks = 1
+	}
+
+	wheel.lock.Lock()
+	defer wheel.lock.Unlock()
+
+	wheel.start()
+	slot := (wheel.pos + ticks) % wheelSlots
+	wheel.slots[slot] = append(wheel.slots[slot], &pendingDelete{
+		session:   s,
+		channelID: channelID,
+		messageID: messageID,
+		rounds:    (ticks - 1) / wheelSlots,
+	})
+}
+
+// FlushTemporary deletes every pending temporary message right now and stops the wheel, call this on shutdown
+// so we dont leave "ephemeral" messages sitting in channels forever
+func FlushTemporary() {
+	wheel.lock.Lock()
+	var pending []*pendingDelete
+	for i := range wheel.slots {
+		pending = append(pending, wheel.slots[i]...)
+		wheel.slots[i] = nil
+	}
+	if wheel.running {
+		wheel.ticker.Stop()
+		close(wheel.stop)
+		wheel.running = false
+	}
+	wheel.lock.Unlock()
+
+	for _, p := range pending {
+		p.delete()
+	}
+}
+
+// start starts the wheel goroutine if it isnt running yet (caller must hold the lock)
+func (w *tempWheel) start() {
+	if w.running {
+		return
+	}
+	w.running = true
+	w.ticker = time.NewTicker(wheelTick)
+	w.stop = make(chan struct{})
+	go w.run(w.ticker, w.stop)
+}
+
+// run moves the wheel forward every tick and deletes whatever is due
+func (w *tempWheel) run(ticker *time.Ticker, stop chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			for _, p := range w.advance() {
+				p.delete()
+			}
+		}
+	}
+}
+
+// advance moves the wheel one slot forward and returns the messages that are due
+func (w *tempWheel) advance() []*pendingDelete {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.pos = (w.pos + 1) % wheelSlots
+	var due, waiting []*pendingDelete
+	for _, p := range w.slots[w.pos] {
+		if p.rounds > 0 {
+			p.rounds--
+			waiting = append(waiting, p)
+			continue
+		}
+		due = append(due, p)
+	}
+	w.slots[w.pos] = waiting
+	return due
+}
+
+// delete deletes the message, failures are logged since the message might already be gone
+func (p *pendingDelete) delete() {
+	// one bad delete shouldnt take the whole wheel down with it
+	defer func() {
+		if r := recover(); r != nil {
+			logging.Error("Recovered while deleting temporary message %s: %v", p.messageID, r)
+		}
+	}()
+
+	if err := p.session.ChannelMessageDelete(p.channelID, p.messageID); err != nil {
+		logging.Warn("Failed to delete temporary message %s: %v", p.messageID, err)
+	}
+}
@@ -0,0 +1,202 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"template/config"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Theme color names, these can be used anywhere a color name is accepted (SetNamedColor, embed templates)
+const (
+	ThemePrimary = "primary"
+	ThemeSuccess = "success"
+	ThemeWarning = "warning"
+	ThemeError   = "error"
+	ThemeInfo    = "info"
+)
+
+// defaultTheme is what we fall back to when a theme color is missing from the config
+var defaultTheme = map[string]int{
+	ThemePrimary: 0xffffff, // i like white better
+	ThemeSuccess: 0x43b581,
+	ThemeWarning: 0xfaa61a,
+	ThemeError:   0xb40000, // the old 180, 0, 0 error red
+	ThemeInfo:    0x1c1c1c, // #1c1c1c - Dark gray
+}
+
+// NamedColors are the plain color names SetNamedColor and ParseColor understand
+var NamedColors = map[string]int{
+	"white":   0xffffff,
+	"black":   0x000000,
+	"red":     0xed4245,
+	"green":   0x57f287,
+	"blue":    0x3498db,
+	"yellow":  0xfee75c,
+	"orange":  0xe67e22,
+	"purple":  0x9b59b6,
+	"pink":    0xeb459e,
+	"gray":    0x95a5a6,
+	"grey":    0x95a5a6,
+	"dark":    0x2c2f33,
+	"blurple": 0x5865f2,
+}
+
+// ThemeColor returns the configured color for a theme name (primary, success, warning, error, info)
+func ThemeColor(name string) int {
+	var hex string
+	if config.Config != nil {
+		colors := config.Config.Theme.Colors
+		switch strings.ToLower(name) {
+		case ThemePrimary:
+			hex = colors.Primary
+		case ThemeSuccess:
+			hex = colors.Success
+		case ThemeWarning:
+			hex = colors.Warning
+		case ThemeError:
+			hex = colors.Error
+		case ThemeInfo:
+			hex = colors.Info
+		}
+	}
+
+	if hex != "" {
+		if c, err := ParseHexColor(hex); err == nil {
+			return c
+		}
+	}
+	return defaultTheme[strings.ToLower(name)]
+}
+
+// ParseHexColor turns "#ffffff", "ffffff", "#fff" or "0xffffff" into an embed color
+func ParseHexColor(s string) (int, error) {
+	hex := strings.ToLower(strings.TrimSpace(s))
+	hex = strings.TrimPrefix(hex, "#")
+	hex = strings.TrimPrefix(hex, "0x")
+
+	// short hex like #fff gets expanded to #ffffff
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return 0, fmt.Errorf("invalid hex color %q", s)
+	}
+
+	c, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid hex color %q", s)
+	}
+	return int(c), nil
+}
+
+// CheckTheme returns a line for every theme color that ParseHexColor wouldnt accept, so check-config agrees with what the bot does
+func CheckTheme(t config.ThemeConfig) []string {
+	var problems []string
+	colors := map[string]string{
+		ThemePrimary: t.Colors.Primary,
+		ThemeSuccess: t.Colors.Success,
+		ThemeWarning: t.Colors.Warning,
+		ThemeError:   t.Colors.Error,
+		ThemeInfo:    t.Colors.Info,
+	}
+	for _, name := range []string{ThemePrimary, ThemeSuccess, ThemeWarning, ThemeError, ThemeInfo} {
+		if hex := colors[name]; hex != "" {
+			if _, err := ParseHexColor(hex); err != nil {
+				problems = append(problems, fmt.Sprintf("theme.colors.%s %q is not a hex color like #ffffff, #fff or 0xffffff", name, hex))
+			}
+		}
+	}
+	return problems
+}
+
+// ParseColor understands theme names (primary, error..), named colors (red, blurple..) and hex colors
+func ParseColor(s string) (int, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	if _, ok := defaultTheme[name]; ok {
+		return ThemeColor(name), nil
+	}
+	if c, ok := NamedColors[name]; ok {
+		return c, nil
+	}
+	return ParseHexColor(s)
+}
+
+// SetHexColor sets the embed color from a hex string, invalid colors are ignored
+func (e *Embed) SetHexColor(hex string) *Embed {
+	if c, err := ParseHexColor(hex); err == nil {
+		e.Color = c
+	}
+	return e
+}
+
+// SetNamedColor sets the embed color from a theme name, a named color or a hex string, invalid colors are ignored
+func (e *Embed) SetNamedColor(name string) *Embed {
+	if c, err := ParseColor(name); err == nil {
+		e.Color = c
+	}
+	return e
+}
+
+// ApplyTheme adds the default footer, thumbnail and author from the theme config
+func (e *Embed) ApplyTheme() *Embed {
+	if config.Config == nil {
+		return e
+	}
+	theme := config.Config.Theme
+
+	footer := theme.Footer
+	if footer == "" {
+		footer = config.Config.Brand.Name
+	}
+	footerIcon := theme.FooterIcon
+	if footerIcon == "" {
+		footerIcon = config.Config.Brand.Icon
+	}
+	e.SetFooter(footer, footerIcon)
+
+	if theme.Thumbnail != "" {
+		e.SetThumbnail(theme.Thumbnail)
+	}
+	if theme.Author.Name != "" {
+		e.SetAuthor(theme.Author.Name, theme.Author.Icon, theme.Author.URL)
+	}
+	return e
+}
+
+// NewThemedEmbed returns a new embed with the theme color for kind and the default branding applied
+func NewThemedEmbed(kind string) *Embed {
+	e := NewEmbed().ApplyTheme()
+	e.Color = ThemeColor(kind)
+	return e
+}
+
+// newThemedMessage builds a themed embed with a title and formatted description
+func newThemedMessage(kind, title, msg string, replacements ...interface{}) *discordgo.MessageEmbed {
+	return NewThemedEmbed(kind).
+		SetTitle(title).
+		SetDescription(fmt.Sprintf(msg, replacements...)).
+		Truncate().MessageEmbed
+}
+
+// NewSuccessEmbed creates a new success embed
+func NewSuccessEmbed(title, msg string, replacements ...interface{}) *discordgo.MessageEmbed {
+	return newThemedMessage(ThemeSuccess, title, msg, replacements...)
+}
+
+// NewInfoEmbed creates a new info embed
+func NewInfoEmbed(title, msg string, replacements ...interface{}) *discordgo.MessageEmbed {
+	return newThemedMessage(ThemeInfo, title, msg, replacements...)
+}
+
+// NewWarningEmbed creates a new warning embed
+func NewWarningEmbed(title, msg string, replacements ...interface{}) *discordgo.MessageEmbed {
+	return newThemedMessage(ThemeWarning, title, msg, replacements...)
+}
+
+// NewPrimaryEmbed creates a new embed with the primary theme color
+func NewPrimaryEmbed(title, msg string, replacements ...interface{}) *discordgo.MessageEmbed {
+	return newThemedMessage(ThemePrimary, title, msg, replacements...)
+}
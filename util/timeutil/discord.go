@@ -0,0 +1,30 @@
+package timeutil
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimestampStyle is how Discord renders a <t:unix:style> timestamp, every user sees it in their own timezone
+type TimestampStyle byte
+
+// the styles Discord supports, the examples are for 20 April 2021 16:20
+const (
+	ShortTime     TimestampStyle = 't' // 16:20
+	LongTime      TimestampStyle = 'T' // 16:20:30
+	ShortDate     TimestampStyle = 'd' // 20/04/2021
+	LongDate      TimestampStyle = 'D' // 20 April 2021
+	ShortDateTime TimestampStyle = 'f' // 20 April 2021 16:20
+	LongDateTime  TimestampStyle = 'F' // Tuesday, 20 April 2021 16:20
+	Relative      TimestampStyle = 'R' // 2 months ago
+)
+
+// Timestamp returns the markup for a Discord timestamp
+func Timestamp(t time.Time, style TimestampStyle) string {
+	return fmt.Sprintf("<t:%d:%c>", t.Unix(), style)
+}
+
+// TimestampWithRelative returns a timestamp followed by the relative one, like "20 April 2021 16:20 (2 months ago)"
+func TimestampWithRelative(t time.Time, style TimestampStyle) string {
+	return Timestamp(t, style) + " (" + Timestamp(t, Relative) + ")"
+}
@@ -0,0 +1,197 @@
+package timeutil
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Unit is a unit of time Humanize can show
+type Unit int
+
+// units from smallest to largest, they start at 1 so the zero value in Options means "not set"
+const (
+	Second Unit = iota + 1
+	Minute
+	Hour
+	Day
+	Week
+	Month
+	Year
+)
+
+// unitLength is how long each unit is for plain durations, months and years are approximations
+// (HumanizeBetween uses the real calendar instead)
+var unitLength = map[Unit]time.Duration{
+	Second: time.Second,
+	Minute: time.Minute,
+	Hour:   time.Hour,
+	Day:    24 * time.Hour,
+	Week:   7 * 24 * time.Hour,
+	Month:  30 * 24 * time.Hour,
+	Year:   365 * 24 * time.Hour,
+}
+
+// Options controls how Humanize formats a duration, the zero value shows every unit from years down to seconds
+type Options struct {
+	Precision int    // how many units to show at most (e.g. 2 turns "1 day, 3 hours and 5 minutes" into "1 day and 3 hours"), 0 shows all of them
+	Largest   Unit   // the largest unit to use, 0 means Year
+	Smallest  Unit   // the smallest unit to use, anything smaller is dropped, 0 means Second
+	Weeks     bool   // use weeks, off by default since "2 weeks and 3 days" reads worse than "17 days" for most things
+	Short     bool   // "2d 3h 12m" instead of "2 days, 3 hours and 12 minutes"
+	Locale    string // which locale to use for unit names, empty uses DefaultLocale
+}
+
+// Humanize formats a duration like "2 days, 3 hours and 12 minutes"
+func Humanize(d time.Duration, opts Options) string {
+	if d < 0 {
+		d = -d
+	}
+
+	counts := make(map[Unit]int64)
+	for _, unit := range opts.units() {
+		length := unitLength[unit]
+		counts[unit] = int64(d / length)
+		d -= time.Duration(counts[unit]) * length
+	}
+	return opts.format(counts)
+}
+
+// HumanizeBetween formats the time between two points using the real calendar, so a month is however long
+// that month actually was instead of 30 days (handy for uptimes and ages)
+func HumanizeBetween(from, to time.Time, opts Options) string {
+	if to.Before(from) {
+		from, to = to, from
+	}
+
+	units := opts.units()
+	counts := make(map[Unit]int64)
+
+	// years and months come off the calendar first, whatever is left is a plain duration
+	months := 0
+	if contains(units, Year) || contains(units, Month) {
+		months = (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+		// AddDate rolls over short months (jan 31 + 1 month is march) so we step back until we land before `to`
+		for months > 0 && from.AddDate(0, months, 0).After(to) {
+			months--
+		}
+		if contains(units, Year) {
+			counts[Year] = int64(months / 12)
+			if contains(units, Month) {
+				counts[Month] = int64(months % 12)
+			} else {
+				months -= months % 12
+			}
+		} else {
+			counts[Month] = int64(months)
+		}
+	}
+
+	rest := to.Sub(from.AddDate(0, months, 0))
+	for _, unit := range units {
+		if unit == Year || unit == Month {
+			continue
+		}
+		length := unitLength[unit]
+		counts[unit] = int64(rest / length)
+		rest -= time.Duration(counts[unit]) * length
+	}
+	return opts.format(counts)
+}
+
+// Plural returns singular when n is 1 and plural otherwise, with the number in front ("1 day", "3 days")
+func Plural(n int64, singular, plural string) string {
+	if n == 1 || n == -1 {
+		return fmt.Sprintf("%d %s", n, singular)
+	}
+	return fmt.Sprintf("%d %s", n, plural)
+}
+
+// JoinList joins parts like "a, b and c" using the locale's word for "and"
+func JoinList(parts []string, localeName string) string {
+	switch len(parts) {
+	case 0:
+		return ""
+	case 1:
+		return parts[0]
+	}
+	return strings.Join(parts[:len(parts)-1], ", ") + " " + locale(localeName).And + " " + parts[len(parts)-1]
+}
+
+// units returns the units we are allowed to use from largest to smallest
+func (o Options) units() []Unit {
+	var units []Unit
+	for u := o.largest(); u >= o.smallest(); u-- {
+		if u == Week && !o.Weeks {
+			continue
+		}
+		units = append(units, u)
+	}
+	return units
+}
+
+// largest returns the largest unit to use with the default filled in
+func (o Options) largest() Unit {
+	if o.Largest == 0 {
+		return Year
+	}
+	return o.Largest
+}
+
+// smallest returns the smallest unit to use with the default filled in
+func (o Options) smallest() Unit {
+	if o.Smallest == 0 {
+		return Second
+	}
+	return o.Smallest
+}
+
+// format turns unit counts into text, it skips zero units and stops after Precision units
+func (o Options) format(counts map[Unit]int64) string {
+	l := locale(o.Locale)
+
+	var parts []string
+	used := 0 // units we went through since the first one we showed, zeros included
+	for _, u := range o.units() {
+		n := counts[u]
+		if len(parts) > 0 {
+			used++
+		}
+		// precision counts every unit from the largest one we showed, so with a precision of 2
+		// "1 day, 0 hours and 5 minutes" becomes "1 day" instead of skipping ahead to the minutes
+		if o.Precision > 0 && used >= o.Precision {
+			break
+		}
+		if n == 0 {
+			// we dont want to show "0 days" so we skip it
+			continue
+		}
+
+		if o.Short {
+			parts = append(parts, fmt.Sprintf("%d%s", n, l.Short[u]))
+		} else {
+			parts = append(parts, Plural(n, l.Units[u][0], l.Units[u][1]))
+		}
+	}
+
+	if len(parts) == 0 {
+		if o.Short {
+			return fmt.Sprintf("0%s", l.Short[o.smallest()])
+		}
+		return l.Now
+	}
+	if o.Short {
+		return strings.Join(parts, " ")
+	}
+	return JoinList(parts, o.Locale)
+}
+
+// contains reports whether a unit is in the list
+func contains(units []Unit, u Unit) bool {
+	for _, v := range units {
+		if v == u {
+			return true
+		}
+	}
+	return false
+}
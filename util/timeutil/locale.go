@@ -0,0 +1,100 @@
+package timeutil
+
+import (
+	"strings"
+	"sync"
+)
+
+// Locale holds the words Humanize uses for a language
+type Locale struct {
+	Units map[Unit][2]string // singular and plural name of every unit
+	Short map[Unit]string    // short suffix for every unit (d, h, m..)
+	And   string             // joins the last two parts ("3 hours and 2 minutes")
+	Now   string             // shown for durations smaller than the smallest unit
+}
+
+// DefaultLocale is used when Options.Locale is empty or unknown
+var DefaultLocale = "en"
+
+var (
+	localesLock sync.RWMutex
+	locales     = map[string]Locale{
+		"en": {
+			Units: map[Unit][2]string{
+				Second: {"second", "seconds"},
+				Minute: {"minute", "minutes"},
+				Hour:   {"hour", "hours"},
+				Day:    {"day", "days"},
+				Week:   {"week", "weeks"},
+				Month:  {"month", "months"},
+				Year:   {"year", "years"},
+			},
+			Short: map[Unit]string{Second: "s", Minute: "m", Hour: "h", Day: "d", Week: "w", Month: "mo", Year: "y"},
+			And:   "and",
+			Now:   "just now",
+		},
+		"de": {
+			Units: map[Unit][2]string{
+				Second: {"Sekunde", "Sekunden"},
+				Minute: {"Minute", "Minuten"},
+				Hour:   {"Stunde", "Stunden"},
+				Day:    {"Tag", "Tage"},
+				Week:   {"Woche", "Wochen"},
+				Month:  {"Monat", "Monate"},
+				Year:   {"Jahr", "Jahre"},
+			},
+			Short: map[Unit]string{Second: "s", Minute: "m", Hour: "h", Day: "T", Week: "W", Month: "M", Year: "J"},
+			And:   "und",
+			Now:   "gerade eben",
+		},
+		"es": {
+			Units: map[Unit][2]string{
+				Second: {"segundo", "segundos"},
+				Minute: {"minuto", "minutos"},
+				Hour:   {"hora", "horas"},
+				Day:    {"día", "días"},
+				Week:   {"semana", "semanas"},
+				Month:  {"mes", "meses"},
+				Year:   {"año", "años"},
+			},
+			Short: map[Unit]string{Second: "s", Minute: "min", Hour: "h", Day: "d", Week: "sem", Month: "mes", Year: "a"},
+			And:   "y",
+			Now:   "ahora mismo",
+		},
+		"fr": {
+			Units: map[Unit][2]string{
+				Second: {"seconde", "secondes"},
+				Minute: {"minute", "minutes"},
+				Hour:   {"heure", "heures"},
+				Day:    {"jour", "jours"},
+				Week:   {"semaine", "semaines"},
+				Month:  {"mois", "mois"},
+				Year:   {"an", "ans"},
+			},
+			Short: map[Unit]string{Second: "s", Minute: "min", Hour: "h", Day: "j", Week: "sem", Month: "mois", Year: "a"},
+			And:   "et",
+			Now:   "à l'instant",
+		},
+	}
+)
+
+// RegisterLocale adds (or replaces) a locale so Humanize can use it
+func RegisterLocale(name string, l Locale) {
+	localesLock.Lock()
+	defer localesLock.Unlock()
+	locales[strings.ToLower(name)] = l
+}
+
+// locale returns the locale with the given name, falling back to the default and then english
+func locale(name string) Locale {
+	localesLock.RLock()
+	defer localesLock.RUnlock()
+
+	if l, ok := locales[strings.ToLower(name)]; ok {
+		return l
+	}
+	if l, ok := locales[DefaultLocale]; ok {
+		return l
+	}
+	return locales["en"]
+}
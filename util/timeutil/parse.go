@@ -0,0 +1,254 @@
+package timeutil
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnknownFormat is returned when the input doesnt look like any time or duration we understand
+var ErrUnknownFormat = errors.New("unknown time format")
+
+// durationPart matches a number followed by a unit, like "1h", "30 minutes" or "2.5d"
+var durationPart = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([a-z]+)`)
+
+// durationUnits maps everything people type after a number to its length
+var durationUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": unitLength[Day], "day": unitLength[Day], "days": unitLength[Day],
+	"w": unitLength[Week], "wk": unitLength[Week], "wks": unitLength[Week], "week": unitLength[Week], "weeks": unitLength[Week],
+	"mo": unitLength[Month], "month": unitLength[Month], "months": unitLength[Month],
+	"y": unitLength[Year], "yr": unitLength[Year], "yrs": unitLength[Year], "year": unitLength[Year], "years": unitLength[Year],
+}
+
+// weekdays maps day names (and their short forms) to weekdays
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+// dateLayouts are the absolute formats we accept
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseDuration parses durations like "1h30m", "2d", "1w 3d" or "1 hour 30 minutes"
+// unlike time.ParseDuration it understands days, weeks, months and years
+func ParseDuration(input string) (time.Duration, error) {
+	s := strings.ToLower(strings.TrimSpace(input))
+	if s == "" {
+		return 0, ErrUnknownFormat
+	}
+
+	var total time.Duration
+	for s != "" {
+		match := durationPart.FindStringSubmatch(s)
+		if match == nil {
+			return 0, fmt.Errorf("%w: %q", ErrUnknownFormat, input)
+		}
+		unit, ok := durationUnits[match[2]]
+		if !ok {
+			return 0, fmt.Errorf("unknown unit %q in %q", match[2], input)
+		}
+		n, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			return 0, err
+		}
+
+		// time.Duration tops out around 292 years, past that the conversion silently wraps around to garbage
+		part := n * float64(unit)
+		if part >= math.MaxInt64 || time.Duration(part) > math.MaxInt64-total {
+			return 0, fmt.Errorf("%q is too long", input)
+		}
+		total += time.Duration(part)
+		s = strings.TrimLeft(s[len(match[0]):], " ,")
+		s = strings.TrimPrefix(s, "and ")
+	}
+	return total, nil
+}
+
+// Parse parses a point in time relative to now, it understands:
+//   - durations from now: "1h30m", "in 2 days", "2d from now"
+//   - durations back from now: "3 days ago"
+//   - dates: "2025-01-31", "2025-01-31 17:00" or RFC3339
+//   - days: "today", "tomorrow", "yesterday", "friday", "this friday" (today if it is friday), "next friday", "last monday"
+//   - times on their own or after a day: "5pm", "17:30", "5:30pm", "noon", "midnight" ("next friday 5pm")
+//
+// days without a time start at midnight, times without a day are the next time the clock hits them
+func Parse(input string, now time.Time) (time.Time, error) {
+	s := strings.Join(strings.Fields(strings.ToLower(input)), " ")
+	if s == "" {
+		return time.Time{}, ErrUnknownFormat
+	}
+	if s == "now" {
+		return now, nil
+	}
+
+	// absolute dates first since "2025-01-31" would confuse the duration parser
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(input), now.Location()); err == nil {
+			return t, nil
+		}
+	}
+
+	// "3 days ago", "in 2 hours", "2h from now" and plain "1h30m"
+	if rest, ok := strings.CutSuffix(s, " ago"); ok {
+		d, err := ParseDuration(rest)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(-d), nil
+	}
+	rest := strings.TrimPrefix(s, "in ")
+	rest = strings.TrimSuffix(rest, " from now")
+	if d, err := ParseDuration(rest); err == nil {
+		return now.Add(d), nil
+	}
+
+	return parseDay(s, now, input)
+}
+
+// parseDay handles "tomorrow 5pm", "next friday", "5pm" and friends
+func parseDay(s string, now time.Time, input string) (time.Time, error) {
+	words := strings.Fields(s)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	day := today
+	dayGiven := true
+	switch {
+	case words[0] == "today":
+		words = words[1:]
+	case words[0] == "tomorrow":
+		day = today.AddDate(0, 0, 1)
+		words = words[1:]
+	case words[0] == "yesterday":
+		day = today.AddDate(0, 0, -1)
+		words = words[1:]
+	case (words[0] == "next" || words[0] == "last" || words[0] == "this") && len(words) > 1:
+		wd, ok := weekdays[words[1]]
+		if !ok {
+			return time.Time{}, fmt.Errorf("%w: %q", ErrUnknownFormat, input)
+		}
+		switch words[0] {
+		case "last":
+			day = previousWeekday(today, wd)
+		case "this":
+			day = thisWeekday(today, wd)
+		default:
+			day = nextWeekday(today, wd)
+		}
+		words = words[2:]
+	default:
+		if wd, ok := weekdays[words[0]]; ok {
+			day = nextWeekday(today, wd)
+			words = words[1:]
+		} else {
+			dayGiven = false
+		}
+	}
+
+	// "at 5pm" reads nicer so we let people say it
+	if len(words) > 0 && words[0] == "at" {
+		words = words[1:]
+	}
+	if len(words) == 0 {
+		if !dayGiven {
+			return time.Time{}, fmt.Errorf("%w: %q", ErrUnknownFormat, input)
+		}
+		return day, nil
+	}
+
+	clock, err := parseClock(strings.Join(words, ""))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %q", ErrUnknownFormat, input)
+	}
+
+	// we build the time from the calendar instead of adding hours to midnight, on days the clocks change
+	// midnight + 17h isnt 5pm
+	t := time.Date(day.Year(), day.Month(), day.Day(), int(clock/time.Hour), int(clock%time.Hour/time.Minute), 0, 0, day.Location())
+	// a time without a day means the next time the clock hits it, so "5pm" at 6pm is tomorrow
+	if !dayGiven && !t.After(now) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// parseClock parses a time of day like "5pm", "5:30pm", "17:30", "noon" or "midnight" into an offset from midnight
+func parseClock(s string) (time.Duration, error) {
+	switch s {
+	case "noon":
+		return 12 * time.Hour, nil
+	case "midnight":
+		return 0, nil
+	}
+
+	pm := strings.HasSuffix(s, "pm")
+	am := strings.HasSuffix(s, "am")
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "pm"), "am")
+
+	hourText, minuteText, hasMinutes := strings.Cut(s, ":")
+	hour, err := strconv.Atoi(hourText)
+	if err != nil {
+		return 0, err
+	}
+	minute := 0
+	if hasMinutes {
+		if minute, err = strconv.Atoi(minuteText); err != nil {
+			return 0, err
+		}
+	} else if !am && !pm {
+		// a bare number like "5" is too ambiguous to guess at
+		return 0, ErrUnknownFormat
+	}
+
+	if am || pm {
+		if hour < 1 || hour > 12 {
+			return 0, ErrUnknownFormat
+		}
+		hour %= 12
+		if pm {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute < 0 || minute > 59 {
+		return 0, ErrUnknownFormat
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, nil
+}
+
+// nextWeekday returns the next day (after today) that falls on the weekday
+func nextWeekday(today time.Time, wd time.Weekday) time.Time {
+	days := (int(wd) - int(today.Weekday()) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+	return today.AddDate(0, 0, days)
+}
+
+// thisWeekday returns the day this week that falls on the weekday, today counts
+func thisWeekday(today time.Time, wd time.Weekday) time.Time {
+	return today.AddDate(0, 0, (int(wd)-int(today.Weekday())+7)%7)
+}
+
+// previousWeekday returns the last day (before today) that fell on the weekday
+func previousWeekday(today time.Time, wd time.Weekday) time.Time {
+	days := (int(today.Weekday()) - int(wd) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+	return today.AddDate(0, 0, -days)
+}